@@ -0,0 +1,614 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 基础适配器, 提供异步通道, hooks, 格式化以及生命周期管理
+package logmo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 异步通道已满时的处理策略
+type OverflowPolicy byte
+
+const (
+	// 阻塞直到通道有空位
+	OVERFLOW_BLOCK OverflowPolicy = iota
+
+	// 阻塞等待, 超时后丢弃
+	OVERFLOW_BLOCK_TIMEOUT
+
+	// 丢弃当前信息
+	OVERFLOW_DROP_NEWEST
+
+	// 丢弃通道中最早的信息
+	OVERFLOW_DROP_OLDEST
+
+	// 改为同步写入
+	OVERFLOW_SYNC
+)
+
+// 默认丢弃统计输出间隔
+const DefaultDropReportInterval = 10 * time.Second
+
+// 默认每批次最多写入的信息数
+const DefaultBatchSize = 1024
+
+// 默认缓存刷新间隔
+const DefaultFlushInterval = time.Second
+
+// 带缓存的输出, BaseAdapter 按刷新间隔调用 FlushBuffer
+// 同步写入后也会立即调用 FlushBuffer
+type BufferedSink interface {
+	Sink
+
+	// 将缓存写入底层输出
+	FlushBuffer() error
+}
+
+// 输出接口, 自定义输出只需实现该接口并通过 NewBaseAdapter 包装为适配器
+// BaseAdapter 保证同一时间只有一个goroutine调用以下方法
+type Sink interface {
+	// 写入格式化后的信息
+	WriteMessage(message Message, b []byte) error
+
+	// 将缓存写入存储
+	Sync() error
+
+	// 关闭输出
+	Close() error
+}
+
+type BaseAdapter struct {
+	// 输出
+	sink Sink
+
+	// 定义通道
+	channel chan Message
+
+	// 定义事件通道
+	event chan AdapterEvent
+
+	// 格式化
+	formatter Formatter
+
+	// hooks以及中间件, 按添加顺序执行
+	hooks middlewareChain
+
+	// 处理模式
+	async bool
+
+	// 异步写入错误处理
+	errorHandler ErrorHandler
+
+	// 通道已满时的处理策略
+	overflow OverflowPolicy
+
+	// OVERFLOW_BLOCK_TIMEOUT 等待时间
+	overflowTimeout time.Duration
+
+	// 丢弃统计输出间隔, 小于等于0时不输出
+	dropReportInterval time.Duration
+
+	// 每批次最多写入的信息数
+	batchSize int
+
+	// 缓存刷新间隔, 仅用于 BufferedSink
+	flushInterval time.Duration
+
+	// 丢弃总数, 以及已输出统计的数量
+	dropped  uint64
+	reported uint64
+
+	// 被hook或中间件过滤的数量
+	filtered uint64
+
+	// 是否已销毁
+	closed int32
+
+	// 销毁时关闭, 唤醒阻塞中的写入并通知Run退出
+	done chan struct{}
+
+	// Run 退出时关闭
+	stopped chan struct{}
+
+	// Run 是否已启动
+	running bool
+
+	// 启动与关闭状态锁
+	stateLock sync.Mutex
+
+	// 关闭期限, 以及关闭结果
+	shutdownCtx  context.Context
+	shutdownLost int
+	shutdownErr  error
+
+	// 写入锁
+	lock sync.Mutex
+
+	// hooks以及通道策略锁
+	hookLock sync.RWMutex
+
+	// 写入与关闭锁, 写入时共享持有, 关闭时独占以等待进行中的写入
+	closeLock sync.RWMutex
+
+	fwg sync.WaitGroup
+}
+
+// 创建基础适配器
+func NewBaseAdapter(sink Sink, channelLen int) *BaseAdapter {
+	return &BaseAdapter{
+		sink:      sink,
+		channel:   make(chan Message, channelLen),
+		event:     make(chan AdapterEvent),
+		formatter: new(FormatterText),
+		async:     true,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+
+		dropReportInterval: DefaultDropReportInterval,
+		batchSize:          DefaultBatchSize,
+		flushInterval:      DefaultFlushInterval,
+	}
+}
+
+// 设置每批次最多写入的信息数, 需在Run之前调用
+func (adapter *BaseAdapter) SetBatchSize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	adapter.batchSize = n
+}
+
+// 设置缓存刷新间隔, 缓存中的信息最长等待该时间后写入, 需在Run之前调用
+func (adapter *BaseAdapter) SetFlushInterval(d time.Duration) {
+	adapter.flushInterval = d
+}
+
+// 设置通道已满时的处理策略, timeout 仅用于 OVERFLOW_BLOCK_TIMEOUT
+func (adapter *BaseAdapter) SetOverflow(policy OverflowPolicy, timeout time.Duration) {
+	adapter.hookLock.Lock()
+	defer adapter.hookLock.Unlock()
+	adapter.overflow = policy
+	adapter.overflowTimeout = timeout
+}
+
+// 设置丢弃统计输出间隔, 需在Run之前调用
+func (adapter *BaseAdapter) SetDropReportInterval(d time.Duration) {
+	adapter.dropReportInterval = d
+}
+
+// 获取丢弃的信息总数
+func (adapter *BaseAdapter) Dropped() uint64 {
+	return atomic.LoadUint64(&adapter.dropped)
+}
+
+// 获取被hook或中间件过滤的信息总数
+func (adapter *BaseAdapter) Filtered() uint64 {
+	return atomic.LoadUint64(&adapter.filtered)
+}
+
+// 是否已销毁
+func (adapter *BaseAdapter) closing() bool {
+	return atomic.LoadInt32(&adapter.closed) != 0
+}
+
+// 格式化并写入输出
+func (adapter *BaseAdapter) write(message Message) error {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+
+	// 格式化
+	msg, err := adapter.formatter.Format(message)
+	if err != nil {
+		return err
+	}
+
+	return adapter.sink.WriteMessage(message, msg)
+}
+
+// 按顺序执行hook以及中间件, 返回处理后的信息
+// 任一hook返回错误或中间件返回HOOK_DROP时信息被过滤
+func (adapter *BaseAdapter) fire(message Message) (Message, bool) {
+	adapter.hookLock.RLock()
+	hooks := adapter.hooks
+	adapter.hookLock.RUnlock()
+
+	message, keep := hooks.run(message)
+	if !keep {
+		atomic.AddUint64(&adapter.filtered, 1)
+	}
+
+	return message, keep
+}
+
+func (adapter *BaseAdapter) SyncWrite(message Message) error {
+	if adapter.closing() {
+		return ErrClosed
+	}
+
+	message, keep := adapter.fire(message)
+	if !keep {
+		return nil
+	}
+
+	// hook执行完成后再加锁, 避免hook中写日志时重复加锁
+	adapter.closeLock.RLock()
+	defer adapter.closeLock.RUnlock()
+	if adapter.closing() {
+		return ErrClosed
+	}
+
+	if err := adapter.write(message); err != nil {
+		return err
+	}
+
+	return adapter.flushBuffer()
+}
+
+// 将输出缓存写入底层
+func (adapter *BaseAdapter) flushBuffer() error {
+	buffered, ok := adapter.sink.(BufferedSink)
+	if !ok {
+		return nil
+	}
+
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	return buffered.FlushBuffer()
+}
+
+// 批量写入, 取出通道中已有的信息一并写入
+func (adapter *BaseAdapter) writeBatch(message Message) {
+	for i := 0; ; i++ {
+		if err := adapter.write(message); err != nil {
+			adapter.reportError(err)
+		}
+
+		if i+1 >= adapter.batchSize {
+			return
+		}
+
+		select {
+		case message = <-adapter.channel:
+		default:
+			return
+		}
+	}
+}
+
+func (adapter *BaseAdapter) AsyncWrite(message Message) error {
+	if adapter.closing() {
+		return ErrClosed
+	}
+
+	message, keep := adapter.fire(message)
+	if !keep {
+		return nil
+	}
+
+	// hook执行完成后再加锁, 避免hook中写日志时重复加锁
+	adapter.closeLock.RLock()
+	defer adapter.closeLock.RUnlock()
+	if adapter.closing() {
+		return ErrClosed
+	}
+
+	// 通道未满时直接写入
+	select {
+	case adapter.channel <- message:
+		return nil
+	case <-adapter.done:
+		return ErrClosed
+	default:
+	}
+
+	// 不使用写入锁, 避免写入缓慢时阻塞调用者
+	adapter.hookLock.RLock()
+	policy, timeout := adapter.overflow, adapter.overflowTimeout
+	adapter.hookLock.RUnlock()
+
+	switch policy {
+	case OVERFLOW_BLOCK_TIMEOUT:
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case adapter.channel <- message:
+		case <-timer.C:
+			atomic.AddUint64(&adapter.dropped, 1)
+		case <-adapter.done:
+			return ErrClosed
+		}
+
+	case OVERFLOW_DROP_NEWEST:
+		atomic.AddUint64(&adapter.dropped, 1)
+
+	case OVERFLOW_DROP_OLDEST:
+		for {
+			select {
+			case adapter.channel <- message:
+				return nil
+			case <-adapter.done:
+				return ErrClosed
+			default:
+			}
+
+			select {
+			case <-adapter.channel:
+				atomic.AddUint64(&adapter.dropped, 1)
+			default:
+			}
+		}
+
+	case OVERFLOW_SYNC:
+		return adapter.write(message)
+
+	default:
+		select {
+		case adapter.channel <- message:
+		case <-adapter.done:
+			return ErrClosed
+		}
+	}
+
+	return nil
+}
+
+func (adapter *BaseAdapter) SetFormatter(formatter Formatter) error {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	adapter.formatter = formatter
+	return nil
+}
+
+// 增加hook, 按添加顺序执行, 同名hook已存在时返回错误
+// hook同时实现Middleware时按中间件执行
+func (adapter *BaseAdapter) AddHook(name string, hook Hook) error {
+	if m, ok := hook.(Middleware); ok {
+		return adapter.AddMiddleware(name, m)
+	}
+
+	return adapter.addHook(namedMiddleware{name: name, hook: hook})
+}
+
+// 增加中间件, 与hook共用执行顺序以及名称, 通过DeleteHook删除
+func (adapter *BaseAdapter) AddMiddleware(name string, m Middleware) error {
+	return adapter.addHook(namedMiddleware{name: name, middleware: m})
+}
+
+func (adapter *BaseAdapter) addHook(m namedMiddleware) error {
+	adapter.hookLock.Lock()
+	defer adapter.hookLock.Unlock()
+	hooks, err := adapter.hooks.add(m)
+	if err != nil {
+		return err
+	}
+
+	adapter.hooks = hooks
+	return nil
+}
+
+func (adapter *BaseAdapter) DeleteHook(name string) error {
+	adapter.hookLock.Lock()
+	defer adapter.hookLock.Unlock()
+
+	if !adapter.hooks.has(name) {
+		return nil
+	}
+
+	adapter.hooks = adapter.hooks.remove(name)
+	return nil
+}
+
+func (adapter *BaseAdapter) Async(b bool) {
+	adapter.async = b
+}
+
+func (adapter *BaseAdapter) IsAsync() bool {
+	return adapter.async
+}
+
+func (adapter *BaseAdapter) SetErrorHandler(handler ErrorHandler) {
+	adapter.errorHandler = handler
+}
+
+// 报告异步写入错误, 未设置错误处理时输出到标准错误
+func (adapter *BaseAdapter) reportError(err error) {
+	if adapter.errorHandler != nil {
+		adapter.errorHandler(err)
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// 写入通道中等待的全部信息
+func (adapter *BaseAdapter) drain() {
+	for len(adapter.channel) > 0 {
+		if err := adapter.write(<-adapter.channel); err != nil {
+			adapter.reportError(err)
+		}
+	}
+}
+
+// 输出丢弃统计
+func (adapter *BaseAdapter) reportDropped() {
+	dropped := atomic.LoadUint64(&adapter.dropped)
+	n := dropped - adapter.reported
+	if n == 0 {
+		return
+	}
+
+	adapter.reported = dropped
+	message := &DefaultMessage{
+		Level:   WARNING,
+		Prefix:  "W",
+		Message: fmt.Sprintf("logmo: %d messages dropped", n),
+		Time:    time.Now(),
+		Pid:     os.Getpid(),
+	}
+
+	if err := adapter.write(message); err != nil {
+		adapter.reportError(err)
+	}
+}
+
+// 刷新输出
+func (adapter *BaseAdapter) sync() {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	if err := adapter.sink.Sync(); err != nil {
+		adapter.reportError(err)
+	}
+}
+
+// 销毁, 写入全部等待中的信息后关闭输出, 销毁后再写入返回 ErrClosed
+func (adapter *BaseAdapter) Destroy() {
+	adapter.Shutdown(context.Background())
+}
+
+// 在ctx期限内写入全部等待中的信息并关闭输出
+// 返回未能写入的信息数量, 超过期限时返回ctx的错误
+func (adapter *BaseAdapter) Shutdown(ctx context.Context) (int, error) {
+	adapter.stateLock.Lock()
+	if adapter.closing() {
+		adapter.stateLock.Unlock()
+		return 0, ErrClosed
+	}
+
+	atomic.StoreInt32(&adapter.closed, 1)
+	adapter.shutdownCtx = ctx
+	close(adapter.done)
+	running := adapter.running
+	adapter.stateLock.Unlock()
+
+	// 未启动Run时直接在当前goroutine中处理
+	if !running {
+		adapter.shutdown()
+		return adapter.shutdownLost, adapter.shutdownErr
+	}
+
+	select {
+	case <-adapter.stopped:
+		return adapter.shutdownLost, adapter.shutdownErr
+	case <-ctx.Done():
+		return len(adapter.channel), ctx.Err()
+	}
+}
+
+// 关闭处理
+func (adapter *BaseAdapter) shutdown() {
+	// 等待进行中的写入完成, 之后不会再有信息进入通道
+	adapter.closeLock.Lock()
+	adapter.closeLock.Unlock()
+
+	ctx := adapter.shutdownCtx
+	for len(adapter.channel) > 0 && ctx.Err() == nil {
+		if err := adapter.write(<-adapter.channel); err != nil {
+			adapter.reportError(err)
+		}
+	}
+
+	adapter.shutdownLost = len(adapter.channel)
+	if adapter.shutdownLost > 0 {
+		adapter.shutdownErr = ctx.Err()
+	}
+
+	adapter.reportDropped()
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	if err := adapter.sink.Sync(); err != nil && adapter.shutdownErr == nil {
+		adapter.shutdownErr = err
+	}
+
+	if err := adapter.sink.Close(); err != nil && adapter.shutdownErr == nil {
+		adapter.shutdownErr = err
+	}
+}
+
+func (adapter *BaseAdapter) Flush() {
+	if adapter.closing() {
+		return
+	}
+
+	adapter.fwg.Add(1)
+	select {
+	case adapter.event <- ADAPTER_EVENT_FLUSH:
+		adapter.fwg.Wait()
+	case <-adapter.stopped:
+		adapter.fwg.Done()
+	}
+}
+
+func (adapter *BaseAdapter) Run() {
+	adapter.stateLock.Lock()
+	if adapter.running || adapter.closing() {
+		adapter.stateLock.Unlock()
+		return
+	}
+
+	adapter.running = true
+	adapter.stateLock.Unlock()
+	defer close(adapter.stopped)
+
+	// 丢弃统计
+	var tick <-chan time.Time
+	if adapter.dropReportInterval > 0 {
+		ticker := time.NewTicker(adapter.dropReportInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// 缓存刷新
+	var flush <-chan time.Time
+	if _, ok := adapter.sink.(BufferedSink); ok && adapter.flushInterval > 0 {
+		ticker := time.NewTicker(adapter.flushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	for {
+		select {
+		case message := <-adapter.channel:
+			adapter.writeBatch(message)
+
+		case <-flush:
+			if err := adapter.flushBuffer(); err != nil {
+				adapter.reportError(err)
+			}
+
+		case <-tick:
+			adapter.reportDropped()
+
+		case <-adapter.done:
+			adapter.shutdown()
+			return
+
+		case e := <-adapter.event:
+			switch e {
+			case ADAPTER_EVENT_FLUSH:
+				adapter.drain()
+				adapter.reportDropped()
+				adapter.sync()
+				adapter.fwg.Done()
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 基础适配器测试
+package logmo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试用输出
+type memorySink struct {
+	lock   sync.Mutex
+	lines  []string
+	syncs  int
+	closed bool
+}
+
+func (sink *memorySink) WriteMessage(message Message, b []byte) error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	sink.lines = append(sink.lines, string(b))
+	return nil
+}
+
+func (sink *memorySink) Sync() error {
+	sink.syncs++
+	return nil
+}
+
+func (sink *memorySink) Close() error {
+	sink.closed = true
+	return nil
+}
+
+func (sink *memorySink) count() int {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	return len(sink.lines)
+}
+
+func TestBaseAdapter(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 100)
+	adapter.AddHook("level", &HookLevel{WARNING})
+
+	log := newLogger()
+	log.AddAdapter("memory", adapter)
+	for i := 0; i < 50; i++ {
+		log.Warn("specific language governing permissions")
+		log.Info("filtered")
+	}
+
+	go adapter.Run()
+	adapter.Flush()
+	if sink.count() != 50 || sink.syncs != 1 {
+		t.Fatalf("unexpected sink state: %d lines, %d syncs", sink.count(), sink.syncs)
+	}
+
+	adapter.Destroy()
+	if !sink.closed {
+		t.Fatal("sink must be closed")
+	}
+}
+
+func TestBaseAdapterOverflow(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 2)
+	adapter.SetOverflow(OVERFLOW_DROP_OLDEST, 0)
+	for i := 0; i < 5; i++ {
+		m := newTestMessage()
+		m.Id = int64(i)
+		if err := adapter.AsyncWrite(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if adapter.Dropped() != 3 {
+		t.Fatalf("unexpected dropped: %d", adapter.Dropped())
+	}
+
+	if m := <-adapter.channel; m.GetID() != 3 {
+		t.Fatalf("oldest messages must be dropped, got %d", m.GetID())
+	}
+
+	adapter.SetOverflow(OVERFLOW_DROP_NEWEST, 0)
+	adapter.AsyncWrite(newTestMessage())
+	adapter.AsyncWrite(newTestMessage())
+	if adapter.Dropped() != 4 {
+		t.Fatalf("unexpected dropped: %d", adapter.Dropped())
+	}
+
+	go adapter.Run()
+	adapter.Flush()
+	last := sink.lines[len(sink.lines)-1]
+	if sink.count() != 3 || !strings.Contains(last, "logmo: 4 messages dropped") {
+		t.Fatalf("unexpected lines: %q", sink.lines)
+	}
+
+	adapter.Destroy()
+	if err := adapter.AsyncWrite(newTestMessage()); err != ErrClosed {
+		t.Fatalf("unexpected error after destroy: %v", err)
+	}
+
+	adapter.Destroy()
+	adapter.Flush()
+}
+
+// 写入缓慢的输出
+type slowSink struct {
+	memorySink
+	delay time.Duration
+}
+
+func (sink *slowSink) WriteMessage(message Message, b []byte) error {
+	time.Sleep(sink.delay)
+	return sink.memorySink.WriteMessage(message, b)
+}
+
+func TestLoggerShutdown(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 1000)
+	log := newLogger()
+	log.AddAdapter("memory", adapter)
+	for i := 0; i < 500; i++ {
+		log.Info("specific language governing permissions")
+	}
+
+	go adapter.Run()
+	if err := log.Shutdown(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if sink.count() != 500 || !sink.closed {
+		t.Fatalf("unexpected sink state: %d lines", sink.count())
+	}
+
+	if err := log.Info("closed"); err != ErrClosed {
+		t.Fatalf("unexpected error after close: %v", err)
+	}
+
+	slow := &slowSink{delay: 10 * time.Millisecond}
+	adapter = NewBaseAdapter(slow, 1000)
+	log = newLogger()
+	log.AddAdapter("slow", adapter)
+	go adapter.Run()
+	for i := 0; i < 100; i++ {
+		log.Info("specific language governing permissions")
+	}
+
+	err := log.Shutdown(50 * time.Millisecond)
+	var se *ShutdownError
+	if !errors.As(err, &se) || se.Lost == 0 || se.Lost+slow.count() > 100 {
+		t.Fatalf("unexpected shutdown result: %v", err)
+	}
+}
+
+func TestBaseAdapterMiddleware(t *testing.T) {
+	first, second := new(memorySink), new(memorySink)
+	a, b := NewBaseAdapter(first, 0), NewBaseAdapter(second, 0)
+
+	var order []string
+	a.AddMiddleware("enrich", MiddlewareFunc(func(message MutableMessage) HookResult {
+		order = append(order, "enrich")
+		message.SetField("region", "eu")
+		message.SetLevel(ERROR)
+		message.SetPrefix("E")
+		return HOOK_KEEP
+	}))
+	a.AddHook("level", &HookLevel{ERROR})
+	a.AddMiddleware("drop", MiddlewareFunc(func(message MutableMessage) HookResult {
+		order = append(order, "drop")
+		if strings.Contains(message.GetMessage(), "noise") {
+			return HOOK_DROP
+		}
+
+		return HOOK_KEEP
+	}))
+
+	if err := a.AddHook("level", &HookLevel{DEBUG}); err == nil {
+		t.Fatal("duplicate hook must fail")
+	}
+
+	log := newLogger()
+	log.AddAdapter("a", a)
+	log.AddAdapter("b", b)
+	sublog := log.With("user", 1)
+	sublog.SyncWarn("specific language governing permissions")
+	sublog.SyncWarn("noise")
+
+	if strings.Join(order, ",") != "enrich,drop,enrich,drop" {
+		t.Fatalf("unexpected hook order %v", order)
+	}
+
+	if first.count() != 1 || !strings.Contains(first.lines[0], "[E]") || !strings.Contains(first.lines[0], "region=eu") {
+		t.Fatalf("unexpected output %q", first.lines)
+	}
+
+	// 其它适配器以及子日志的字段不受影响
+	if second.count() != 2 || strings.Contains(second.lines[0], "region") || !strings.Contains(second.lines[0], "[W]") {
+		t.Fatalf("unexpected output %q", second.lines)
+	}
+
+	if _, ok := sublog.fields["region"]; ok {
+		t.Fatal("middleware must not modify logger fields")
+	}
+
+	if stats := log.Stats(); stats["a"].Filtered != 1 || stats["b"].Filtered != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestShutdownConcurrentWrites(t *testing.T) {
+	for round := 0; round < 5; round++ {
+		sink := new(memorySink)
+		adapter := NewBaseAdapter(sink, 1<<14)
+		go adapter.Run()
+
+		log := newLogger()
+		log.AddAdapter("memory", adapter)
+
+		var written int64
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(direct bool) {
+				defer wg.Done()
+				message := &DefaultMessage{Level: INFO, Message: "direct"}
+				for {
+					var err error
+					if direct {
+						err = adapter.AsyncWrite(message)
+					} else {
+						err = log.Info("logger")
+					}
+
+					if err != nil {
+						return
+					}
+
+					atomic.AddInt64(&written, 1)
+				}
+			}(i%2 == 0)
+		}
+
+		time.Sleep(time.Millisecond)
+		if err := log.Shutdown(time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Wait()
+		if n := atomic.LoadInt64(&written); int64(sink.count()) != n {
+			t.Fatalf("accepted %d messages, wrote %d", n, sink.count())
+		}
+	}
+}
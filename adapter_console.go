@@ -12,168 +12,116 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+
 // 控制台日志支持
 package logmo
 
 import(
-    "fmt"
     "io"
-    "sync"
     "os"
 )
 
 type AdapterConsole struct { 
-    // 定义通道
-    channel chan Message
+    *BaseAdapter
     
-    // 定义事件通道
-    event chan AdapterEvent
+    // out
+    out *consoleStream
     
-    // 格式化
-    formatter Formatter
+    // 错误输出, 为空时全部写入out
+    errOut *consoleStream
     
-    // hooks
-    hooks map[string]Hook
+    // 等级不低于errLevel的信息写入errOut
+    errLevel byte
     
-    // 处理模式
-    async bool
+    // 颜色模式
+    colorMode ColorMode
     
-    // out
+    // 颜色方案
+    scheme *ColorScheme
+}
+
+// 控制台输出流, 各自判断是否着色
+type consoleStream struct {
     out io.Writer
     
-    lock sync.Mutex
-    
-    fwg sync.WaitGroup
-    dwg sync.WaitGroup
+    // 是否着色, 首次写入时判断
+    color *bool
 }
 
-func (adapter *AdapterConsole) write( message Message ) error {
-    adapter.lock.Lock()
-    defer adapter.lock.Unlock()
-    
-    // 格式化
-    msg, err := adapter.formatter.Format( message )
-    if err != nil {
-        return err
+func (adapter *AdapterConsole) WriteMessage( message Message, b []byte ) error {
+    stream := adapter.out
+    if adapter.errOut != nil && message.GetLevel() <= adapter.errLevel {
+        stream = adapter.errOut
     }
     
-    return consoleWriteColor(adapter.out, message.GetLevel(), msg)
-}
-
-func (adapter *AdapterConsole) SyncWrite( message Message ) error {
-     // 执行hook
-    for _, hook := range adapter.hooks {
-       err :=  hook.Fire(message)
-       if err != nil {
-           return nil
-       }
+    if stream.color == nil {
+        color := colorEnabled(adapter.colorMode, stream.out)
+        stream.color = &color
     }
     
-    return adapter.write( message )
-}
-
-func (adapter *AdapterConsole) AsyncWrite( message Message ) error {
-    defer func() {
-        if r := recover(); r != nil {
-           fmt.Println(r)
+    if *stream.color {
+        if style, start, end, ok := adapter.scheme.span(message, b); ok {
+            return consoleWriteColor(stream.out, style, b, start, end)
         }
-    }()
-    
-     // 执行hook
-    for _, hook := range adapter.hooks {
-       err :=  hook.Fire(message)
-       if err != nil {
-           return nil
-       }
     }
     
-    adapter.channel <- message
-    return nil
+    _, err := stream.out.Write(append(b[:len(b):len(b)], '\n'))
+    return err
 }
 
-func (adapter *AdapterConsole) SetFormatter( formatter Formatter ) error {
-    adapter.formatter = formatter
+func (adapter *AdapterConsole) Sync() error {
     return nil
 }
 
-func (adapter *AdapterConsole) AddHook( name string, hook Hook ) error {
-    adapter.lock.Lock()
-    defer adapter.lock.Unlock()
-    
-    if _, ok := adapter.hooks[name]; ok {
-        return nil
-    }
-    
-    adapter.hooks[name] = hook
+func (adapter *AdapterConsole) Close() error {
     return nil
 }
 
-func (adapter *AdapterConsole) DeleteHook( name string ) error {
+// 设置颜色模式, 默认COLOR_AUTO
+func (adapter *AdapterConsole) SetColorMode( mode ColorMode ) {
     adapter.lock.Lock()
     defer adapter.lock.Unlock()
-    
-    if _, ok := adapter.hooks[name]; !ok {
-        return nil
+    adapter.colorMode = mode
+    adapter.out.color = nil
+    if adapter.errOut != nil {
+        adapter.errOut.color = nil
     }
-    
-    delete(adapter.hooks, name)
-    return nil
-}
-
-func (adapter *AdapterConsole) Async( b bool ) {
-    adapter.async = b
 }
 
-func (adapter *AdapterConsole) IsAsync() bool {
-    return adapter.async
+// 设置颜色方案, 为空时使用默认方案
+func (adapter *AdapterConsole) SetColorScheme( scheme *ColorScheme ) {
+    if scheme == nil {
+        scheme = DefaultColorScheme()
+    }
+    
+    adapter.lock.Lock()
+    defer adapter.lock.Unlock()
+    adapter.scheme = scheme
 }
 
-func (adapter *AdapterConsole) Destroy() {
-    adapter.dwg.Add(1)
-    adapter.event <- ADAPTER_EVENT_DESTORY
-    adapter.dwg.Wait()
+// 设置错误输出, 等级不低于level的信息(如WARNING及以上)写入w, 其余写入标准输出
+// w为空时全部写入标准输出
+func (adapter *AdapterConsole) SetErrorWriter( w io.Writer, level byte ) {
+    adapter.lock.Lock()
+    defer adapter.lock.Unlock()
+    adapter.errOut   = nil
+    adapter.errLevel = level
+    if w != nil {
+        adapter.errOut = &consoleStream{out: w}
+    }
 }
 
-func (adapter *AdapterConsole) Flush() {
-    adapter.fwg.Add(1)
-    adapter.event <- ADAPTER_EVENT_FLUSH
-    adapter.fwg.Wait()
+func NewAdapterConsole( channelLen int ) *AdapterConsole{
+    return NewAdapterConsoleWriter(channelLen, os.Stdout)
 }
 
-func (adapter *AdapterConsole) Run() {
-    for{
-        select {
-            case message := <-adapter.channel:
-              err := adapter.write( message )
-              if err != nil {
-                  fmt.Println(err)
-              }
-              
-            case e := <-adapter.event:
-              switch e {
-                  case ADAPTER_EVENT_DESTORY:
-                    close(adapter.channel)
-                    close(adapter.event)
-                    adapter.dwg.Done()
-                    return
-                    
-                  case ADAPTER_EVENT_FLUSH:
-                    if len(adapter.channel) > 0 {
-                        adapter.write( <-adapter.channel )
-                    }
-                    adapter.fwg.Done()
-              }
-        }
+// 创建写入任意io.Writer的控制台适配器
+func NewAdapterConsoleWriter( channelLen int, out io.Writer ) *AdapterConsole {
+    adapter := &AdapterConsole{
+        out    : &consoleStream{out: out},
+        scheme : DefaultColorScheme(),
     }
+    
+    adapter.BaseAdapter = NewBaseAdapter(adapter, channelLen)
+    return adapter
 }
-
-func NewAdapterConsole( channelLen int ) *AdapterConsole{
-    return &AdapterConsole{
-        channel : make(chan Message, channelLen),
-        event   : make(chan AdapterEvent),
-        formatter : new(FormatterText),
-        hooks   : make(map[string]Hook),
-        async   : true,
-        out     : os.Stdout,
-    }
-}
\ No newline at end of file
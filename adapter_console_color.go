@@ -12,6 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+
 // +build !windows
 
 // 控制台文字颜色控制
@@ -19,26 +20,9 @@ package logmo
 
 import(
     "io"
-    "fmt"
 )
 
-// 定义日志等级色彩
-var logColors = map[byte]string{
-    EMERGENCY : "1;34",
-    ALERT     : "1;36",
-    CRITICAL  : "1;35",
-    ERROR     : "1;31",
-    WARNING   : "1;33",
-    NOTICE    : "1;32",
-    INFO      : "1;37",
-    DEBUG     : "1;37",
+// 向控制台写入颜色信息, 只为b[start:end]着色
+func consoleWriteColor(out io.Writer, style ColorStyle, b []byte, start, end int) error {
+    return writeANSI(out, style, b, start, end)
 }
-
-
-
-// 向控制台写入颜色信息
-func consoleWriteColor(out io.Writer, level byte, msg []byte) {
-    msg = "\033[" + logColors[Level] + "m" + msg + "\033[0m"
-    _, err := fmt.Fprintln(out, string(msg))
-    return err
-}
\ No newline at end of file
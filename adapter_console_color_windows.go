@@ -20,44 +20,96 @@ package logmo
 
 import(
     "io"
-    "fmt"
+    "sync"
     "syscall"
 )
 
 var(
     kernel32DLL                 = syscall.NewLazyDLL("kernel32.dll")
-	setConsoleTextAttributeProc = kernel32DLL.NewProc("SetConsoleTextAttribute")
+    setConsoleTextAttributeProc = kernel32DLL.NewProc("SetConsoleTextAttribute")
+    setConsoleModeProc          = kernel32DLL.NewProc("SetConsoleMode")
+    
+    // 各控制台是否支持ANSI转义序列
+    virtualTerminals sync.Map
 )
 
-var logColors = map[byte]uint16{
-    EMERGENCY : 0x0004,
-    ALERT     : 0x0008,
-    CRITICAL  : 0x0005,
-    ERROR     : 0x0004,
-    WARNING   : 0x0006,
-    NOTICE    : 0x0002,
-    INFO      : 0x0007,
-    DEBUG     : 0x0003,
-}
+const enableVirtualTerminalProcessing = 0x0004
 
 type fileInterface interface {
 	Fd() uintptr
 }
 
-// 向控制台写入颜色信息
-func consoleWriteColor(out io.Writer, level byte, msg []byte) error {
-    if f, ok := out.(fileInterface); ok {
-        setConsoleTextAttribute(f, logColors[level])
-        _, err := fmt.Fprintln(out, string(msg))
-        setConsoleTextAttribute(f, 0x0007)
-        
+// 向控制台写入颜色信息, 只为b[start:end]着色
+// 支持ANSI转义序列的控制台(Windows 10及以上)直接输出ANSI, 否则以控制台属性设置16色
+func consoleWriteColor(out io.Writer, style ColorStyle, b []byte, start, end int) error {
+    f, ok := out.(fileInterface)
+    if !ok || enableVirtualTerminal(f) {
+        return writeANSI(out, style, b, start, end)
+    }
+    
+    if _, err := out.Write(b[:start]); err != nil {
+        return err
+    }
+    
+    setConsoleTextAttribute(f, consoleAttribute(style))
+    _, err := out.Write(b[start:end])
+    setConsoleTextAttribute(f, 0x0007)
+    if err != nil {
         return err
     }
     
-    _, err := fmt.Fprintln(out, string(msg))
+    _, err = out.Write(append(b[end:len(b):len(b)], '\n'))
     return err
 }
 
+// 开启控制台ANSI转义序列支持, 返回是否支持
+func enableVirtualTerminal( f fileInterface ) bool {
+    if v, ok := virtualTerminals.Load(f.Fd()); ok {
+        return v.(bool)
+    }
+    
+    var mode uint32
+    enabled := false
+    if err := syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode); err == nil {
+        enabled = mode & enableVirtualTerminalProcessing != 0
+        if !enabled {
+            ok, _, _ := setConsoleModeProc.Call(f.Fd(), uintptr(mode | enableVirtualTerminalProcessing))
+            enabled = ok != 0
+        }
+    }
+    
+    virtualTerminals.Store(f.Fd(), enabled)
+    return enabled
+}
+
+// 转换为控制台属性, 只支持16色
+func consoleAttribute( style ColorStyle ) uint16 {
+    attr := uint16(0x0007)
+    if n, ok := style.Foreground.ansi16(); ok {
+        attr = windowsColor(n)
+    }
+    
+    if style.Bold {
+        attr |= 0x0008
+    }
+    
+    if n, ok := style.Background.ansi16(); ok {
+        attr |= windowsColor(n) << 4
+    }
+    
+    return attr
+}
+
+// ANSI颜色编号转换为控制台颜色, ANSI与控制台的红蓝位相反
+func windowsColor( n byte ) uint16 {
+    c := uint16(n & 1) << 2 | uint16(n & 2) | uint16(n & 4) >> 2
+    if n >= 8 {
+        c |= 0x0008
+    }
+    
+    return c
+}
+
 // setConsoleTextAttribute sets the attributes of characters written to the
 // console screen buffer by the WriteFile or WriteConsole function.
 // See http://msdn.microsoft.com/en-us/library/windows/desktop/ms686047(v=vs.85).aspx.
@@ -0,0 +1,280 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 控制台颜色方案
+package logmo
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 颜色模式
+type ColorMode byte
+
+const (
+	// 按终端以及 NO_COLOR / FORCE_COLOR 环境变量判断
+	COLOR_AUTO ColorMode = iota
+
+	// 总是输出颜色
+	COLOR_ALWAYS
+
+	// 不输出颜色
+	COLOR_NEVER
+)
+
+// 16色编号, 加8为对应的高亮色
+const (
+	ANSI_BLACK = iota
+	ANSI_RED
+	ANSI_GREEN
+	ANSI_YELLOW
+	ANSI_BLUE
+	ANSI_MAGENTA
+	ANSI_CYAN
+	ANSI_WHITE
+)
+
+type colorKind byte
+
+const (
+	colorDefault colorKind = iota
+	color16
+	color256
+	colorRGB
+)
+
+// 颜色, 零值为终端默认颜色
+type Color struct {
+	kind    colorKind
+	r, g, b byte
+}
+
+// 16色, 0-7为普通色, 8-15为高亮色
+func Color16(n byte) Color {
+	return Color{kind: color16, r: n & 0x0f}
+}
+
+// 256色
+func Color256(n byte) Color {
+	return Color{kind: color256, r: n}
+}
+
+// 真彩色
+func ColorRGB(r, g, b byte) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// ANSI SGR参数, base为30(前景)或40(背景)
+func (c Color) sgr(base int) string {
+	switch c.kind {
+	case color16:
+		if c.r >= 8 {
+			return strconv.Itoa(base + 60 + int(c.r) - 8)
+		}
+
+		return strconv.Itoa(base + int(c.r))
+
+	case color256:
+		return strconv.Itoa(base+8) + ";5;" + strconv.Itoa(int(c.r))
+
+	case colorRGB:
+		return strconv.Itoa(base+8) + ";2;" + strconv.Itoa(int(c.r)) + ";" + strconv.Itoa(int(c.g)) + ";" + strconv.Itoa(int(c.b))
+	}
+
+	return ""
+}
+
+// 转换为最接近的16色, 用于只支持16色的控制台, 默认颜色返回false
+func (c Color) ansi16() (byte, bool) {
+	r, g, b := c.r, c.g, c.b
+	switch c.kind {
+	case color16:
+		return c.r, true
+
+	case color256:
+		switch {
+		case c.r < 16:
+			return c.r, true
+		case c.r >= 232:
+			// 灰阶
+			gray := 8 + (c.r-232)*10
+			r, g, b = gray, gray, gray
+		default:
+			n := c.r - 16
+			r, g, b = n/36*51, n/6%6*51, n%6*51
+		}
+
+	case colorRGB:
+	default:
+		return 0, false
+	}
+
+	var n byte
+	if r >= 0x80 {
+		n |= ANSI_RED
+	}
+
+	if g >= 0x80 {
+		n |= ANSI_GREEN
+	}
+
+	if b >= 0x80 {
+		n |= ANSI_BLUE
+	}
+
+	if r >= 0xc0 || g >= 0xc0 || b >= 0xc0 {
+		n += 8
+	}
+
+	return n, true
+}
+
+// 颜色样式
+type ColorStyle struct {
+	// 前景色
+	Foreground Color
+
+	// 背景色
+	Background Color
+
+	// 粗体
+	Bold bool
+
+	// 暗淡
+	Dim bool
+}
+
+// ANSI转义序列, 无样式时返回空
+func (style ColorStyle) ansi() string {
+	codes := make([]string, 0, 4)
+	if style.Bold {
+		codes = append(codes, "1")
+	}
+
+	if style.Dim {
+		codes = append(codes, "2")
+	}
+
+	if fg := style.Foreground.sgr(30); fg != "" {
+		codes = append(codes, fg)
+	}
+
+	if bg := style.Background.sgr(40); bg != "" {
+		codes = append(codes, bg)
+	}
+
+	if len(codes) == 0 {
+		return ""
+	}
+
+	return "\033[" + strings.Join(codes, ";") + "m"
+}
+
+// 颜色方案
+type ColorScheme struct {
+	// 各等级样式, 未定义的等级不着色
+	Levels map[byte]ColorStyle
+
+	// 只为等级标签 [prefix] 着色, 否则为整行着色
+	LevelOnly bool
+}
+
+// 默认颜色方案
+func DefaultColorScheme() *ColorScheme {
+	return &ColorScheme{
+		Levels: map[byte]ColorStyle{
+			EMERGENCY: {Foreground: Color16(ANSI_BLUE), Bold: true},
+			ALERT:     {Foreground: Color16(ANSI_CYAN), Bold: true},
+			CRITICAL:  {Foreground: Color16(ANSI_MAGENTA), Bold: true},
+			ERROR:     {Foreground: Color16(ANSI_RED), Bold: true},
+			WARNING:   {Foreground: Color16(ANSI_YELLOW), Bold: true},
+			NOTICE:    {Foreground: Color16(ANSI_GREEN), Bold: true},
+			INFO:      {Foreground: Color16(ANSI_WHITE), Bold: true},
+			DEBUG:     {Foreground: Color16(ANSI_WHITE), Dim: true},
+		},
+	}
+}
+
+// 获取需要着色的范围, 不需要着色时返回false
+func (scheme *ColorScheme) span(message Message, b []byte) (ColorStyle, int, int, bool) {
+	style, ok := scheme.Levels[message.GetLevel()]
+	if !ok || style == (ColorStyle{}) {
+		return style, 0, 0, false
+	}
+
+	if !scheme.LevelOnly {
+		return style, 0, len(b), true
+	}
+
+	tag := "[" + message.GetPrefix() + "]"
+	if i := bytes.Index(b, []byte(tag)); i >= 0 {
+		return style, i, i + len(tag), true
+	}
+
+	// 格式中没有等级标签时整行着色
+	return style, 0, len(b), true
+}
+
+// 是否为终端
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+
+	finfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return finfo.Mode()&os.ModeCharDevice != 0
+}
+
+// 按模式以及环境变量判断是否着色
+// NO_COLOR 非空时不着色, FORCE_COLOR 非空且不为0/false时总是着色, 否则只在终端着色
+func colorEnabled(mode ColorMode, out io.Writer) bool {
+	switch mode {
+	case COLOR_ALWAYS:
+		return true
+	case COLOR_NEVER:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && !strings.EqualFold(force, "false")
+	}
+
+	return isTerminal(out)
+}
+
+// 以ANSI转义序列输出, 只为b[start:end]着色
+func writeANSI(out io.Writer, style ColorStyle, b []byte, start, end int) error {
+	buf := make([]byte, 0, len(b)+32)
+	buf = append(buf, b[:start]...)
+	buf = append(buf, style.ansi()...)
+	buf = append(buf, b[start:end]...)
+	buf = append(buf, "\033[0m"...)
+	buf = append(buf, b[end:]...)
+	buf = append(buf, '\n')
+	_, err := out.Write(buf)
+	return err
+}
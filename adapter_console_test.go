@@ -16,7 +16,8 @@
 package logmo
 
 import(
-    "os"
+    "bytes"
+    "strings"
     "testing"
     "time"
 )
@@ -44,13 +45,121 @@ func TestAConsole( t *testing.T ) {
 }
 
 
+func TestConsoleColor( t *testing.T ) {
+    t.Setenv("NO_COLOR", "")
+    t.Setenv("FORCE_COLOR", "")
+    
+    var buf bytes.Buffer
+    c := NewAdapterConsoleWriter(0, &buf)
+    m := &DefaultMessage{Level: ERROR, Message: "specific language governing permissions", Prefix: "E", Time: time.Now()}
+    
+    // 非终端不着色
+    c.SyncWrite(m)
+    if strings.Contains(buf.String(), "\033[") || !strings.HasSuffix(buf.String(), "permissions\n") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+    
+    buf.Reset()
+    t.Setenv("FORCE_COLOR", "1")
+    c.SetColorMode(COLOR_AUTO)
+    c.SyncWrite(m)
+    if !strings.HasPrefix(buf.String(), "\033[1;31m") || !strings.HasSuffix(buf.String(), "\033[0m\n") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+    
+    buf.Reset()
+    t.Setenv("NO_COLOR", "1")
+    t.Setenv("FORCE_COLOR", "")
+    c.SetColorMode(COLOR_AUTO)
+    c.SyncWrite(m)
+    if strings.Contains(buf.String(), "\033[") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+    
+    // 只为等级标签着色
+    buf.Reset()
+    c.SetColorMode(COLOR_ALWAYS)
+    c.SetColorScheme(&ColorScheme{
+        Levels: map[byte]ColorStyle{
+            ERROR   : {Foreground: ColorRGB(255, 0, 0), Background: Color256(236), Bold: true},
+            WARNING : {Foreground: Color16(ANSI_YELLOW + 8), Dim: true},
+        },
+        LevelOnly: true,
+    })
+    c.SyncWrite(m)
+    if !strings.Contains(buf.String(), " \033[1;38;2;255;0;0;48;5;236m[E]\033[0m specific") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+    
+    buf.Reset()
+    m.Level, m.Prefix = WARNING, "W"
+    c.SyncWrite(m)
+    if !strings.Contains(buf.String(), "\033[2;93m[W]\033[0m") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+    
+    // 未定义样式的等级不着色
+    buf.Reset()
+    m.Level, m.Prefix = INFO, "I"
+    c.SyncWrite(m)
+    if strings.Contains(buf.String(), "\033[") {
+        t.Fatalf("unexpected output %q", buf.String())
+    }
+}
+
+func TestConsoleErrorWriter( t *testing.T ) {
+    var out, errOut bytes.Buffer
+    c := NewAdapterConsoleWriter(0, &out)
+    c.SetErrorWriter(&errOut, WARNING)
+    c.SetColorMode(COLOR_NEVER)
+    
+    log := newLogger()
+    log.AddAdapter("console", c)
+    log.SyncErr("error")
+    log.SyncWarn("warning")
+    log.SyncNotice("notice")
+    log.SyncDebug("debug")
+    if strings.Count(errOut.String(), "\n") != 2 || !strings.Contains(errOut.String(), "warning") {
+        t.Fatalf("unexpected stderr %q", errOut.String())
+    }
+    
+    if strings.Count(out.String(), "\n") != 2 || !strings.Contains(out.String(), "notice") {
+        t.Fatalf("unexpected stdout %q", out.String())
+    }
+    
+    // 关闭错误输出后全部写入out
+    c.SetErrorWriter(nil, WARNING)
+    log.SyncErr("error")
+    if strings.Count(out.String(), "\n") != 3 {
+        t.Fatalf("unexpected stdout %q", out.String())
+    }
+}
+
+func TestColorANSI16( t *testing.T ) {
+    for _, test := range []struct{
+        color    Color
+        expected byte
+    }{
+        {Color16(ANSI_RED), ANSI_RED},
+        {Color256(12), 12},
+        {Color256(196), ANSI_RED + 8},
+        {Color256(244), ANSI_WHITE},
+        {ColorRGB(0, 0x90, 0x90), ANSI_CYAN},
+    }{
+        if n, ok := test.color.ansi16(); !ok || n != test.expected {
+            t.Fatalf("%+v: expected %d, got %d", test.color, test.expected, n)
+        }
+    }
+    
+    if _, ok := (Color{}).ansi16(); ok {
+        t.Fatal("default color must not convert")
+    }
+}
+
 func BenchmarkSyncConsole(b *testing.B) {
 	 f := &FormatterText{}
-     c := &AdapterConsole{
-        channel:make(chan Message, 10000),
-        formatter:f,
-        out: os.Stdout,
-     }
+     c := NewAdapterConsole(10000)
+     c.SetFormatter(f)
      
      m := &DefaultMessage{
         Level:NOTICE,
@@ -68,11 +177,8 @@ func BenchmarkSyncConsole(b *testing.B) {
 
 func BenchmarkAsyncConsole(b *testing.B) {
 	 f := &FormatterText{}
-     c := &AdapterConsole{
-        channel:make(chan Message, 1000),
-        formatter:f,
-        out: os.Stdout,
-     }
+     c := NewAdapterConsole(1000)
+     c.SetFormatter(f)
      
      m := &DefaultMessage{
         Level:NOTICE,
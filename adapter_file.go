@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// 控制台日志支持
+// 文件日志支持
 package logmo
 
 import(
@@ -22,37 +22,28 @@ import(
     "time"
     "path/filepath"
     "strings"
-    "log"
+    "sort"
     "io"
     "bytes"
+    "bufio"
+    "os/signal"
+    "syscall"
 )
 
 type AdapterFile struct {
-    // 定义通道
-    channel chan Message
-    
-    // 定义事件通道
-    event chan AdapterEvent
-    
-    // 格式化
-    formatter Formatter
-    
-    // hooks
-    hooks map[string]Hook
-    
-    // 处理模式
-    async bool
-    
-    //锁
-    lock sync.Mutex
-    fwg sync.WaitGroup
-    dwg sync.WaitGroup
+    *BaseAdapter
     
     // 写入
     mutexWriter *fileMutex
     
-    // 定义输出
-    out *log.Logger
+    // 定义输出, 为缓存或文件
+    out io.Writer
+    
+    // 写入缓存
+    buffer *bufio.Writer
+    
+    // 写入缓存大小, 小于等于0时不使用缓存直接写入文件
+    BufferSize int
     
     // 文件名称
     Filename string
@@ -63,155 +54,138 @@ type AdapterFile struct {
     // 文件最大
     MaxSize int
     
-    // 最大保存天数
+    // 最大保存天数, 小于等于0时不限制
     MaxDays int64
     
+    // 最多保留的滚动文件数, 小于等于0时不限制
+    MaxBackups int
+    
+    // 滚动文件总大小上限, 小于等于0时不限制
+    MaxTotalSize int64
+    
     //  循环滚动次数
     Rotation int
     
+    // 滚动策略, 为空时按天以及MaxSize, MaxLine滚动
+    Policy RotationPolicy
+    
+    // 滚动文件名模板, 支持 {name} {time} {index}, 默认 DefaultRotationNamePattern
+    NamePattern string
+    
+    // 滚动文件名时间格式, 默认 DefaultRotationTimeLayout
+    TimeLayout string
+    
+    // 滚动文件压缩, 为空时不压缩
+    Compressor Compressor
+    
+    // 压缩锁, 压缩进行中时阻止下一次滚动重新编号
+    archiveLock sync.Mutex
+    
     // 记录当前文件line
     line int
     
     // 记录当前文件size
     size int
     
-    lastDay int
+    // 当前文件开始写入时间
+    openedAt time.Time
+    
+    // 检查文件是否被外部移动的间隔, 小于等于0时不检查
+    ReopenCheckInterval time.Duration
+    
+    // 上次检查时间
+    lastCheck time.Time
+    
+    // 多进程共享模式, 用于多个进程写入同一文件
+    // 滚动以及压缩时对LockFile加进程间锁, 每次写入前检查文件是否已被其它进程滚动
+    // 此模式下MaxLine只统计本进程写入的行数, 滚动文件在之后的滚动中
+    // 超过缓存刷新间隔两倍未修改时才压缩
+    Shared bool
+    
+    // 共享模式的锁文件, 默认为 Filename + ".lock"
+    LockFile string
 }
 
 
-func (adapter *AdapterFile) write( message Message ) error {
-    adapter.lock.Lock()
-    defer adapter.lock.Unlock()
+func (adapter *AdapterFile) WriteMessage( message Message, msg []byte ) error {
+    // 未启动Run时写入
+    if adapter.out == nil {
+        adapter.Initialize()
+    }
     
-    // 格式化
-    msg, err := adapter.formatter.Format( message )
-    if err != nil {
+    if err := adapter.checkMoved(); err != nil {
         return err
     }
     
     size := len(msg)
-    adapter.check(size)
-    adapter.out.Println(string(msg))
-    return nil
-}
-
-func (adapter *AdapterFile) AsyncWrite( message Message ) error {
-    defer func() {
-        if r := recover(); r != nil {
-           fmt.Println(r)
-        }
-    }()
+    checkErr := adapter.check(size)
     
-     // 执行hook
-    for _, hook := range adapter.hooks {
-       err :=  hook.Fire(message)
-       if err != nil {
-           return nil
-       }
+    // 共享模式下缓存放不下整行时先写入文件, 避免一行被拆开与其它进程的内容交错
+    if adapter.Shared && adapter.buffer != nil && size + 1 > adapter.buffer.Available() {
+        if err := adapter.FlushBuffer(); err != nil {
+            return err
+        }
     }
     
-    adapter.channel <- message
-    return nil
-}
-
-func (adapter *AdapterFile) SyncWrite( message Message ) error {
-    // 执行hook
-    for _, hook := range adapter.hooks {
-       err :=  hook.Fire(message)
-       if err != nil {
-           return nil
-       }
+    if _, err := adapter.out.Write(append(msg[:size:size], '\n')); err != nil {
+        // 缓存写入失败后会一直返回错误, 丢弃缓存以便恢复写入
+        if adapter.buffer != nil {
+            adapter.buffer.Reset(adapter.mutexWriter)
+        }
+        return err
     }
     
-    return adapter.write( message )
-}
-
-func (adapter *AdapterFile) SetFormatter( formatter Formatter ) error {
-    adapter.formatter = formatter
-    return nil
+    return checkErr
 }
 
-func (adapter *AdapterFile) AddHook( name string, hook Hook ) error {
-    adapter.lock.Lock()
-    defer adapter.lock.Unlock()
-    
-    if _, ok := adapter.hooks[name]; ok {
+func (adapter *AdapterFile) FlushBuffer() error {
+    if adapter.buffer == nil {
         return nil
     }
     
-    adapter.hooks[name] = hook
-    return nil
-}
-
-func (adapter *AdapterFile) DeleteHook( name string ) error {
-    adapter.lock.Lock()
-    defer adapter.lock.Unlock()
-    
-    if _, ok := adapter.hooks[name]; !ok {
-        return nil
+    if err := adapter.buffer.Flush(); err != nil {
+        adapter.buffer.Reset(adapter.mutexWriter)
+        return err
     }
     
-    delete(adapter.hooks, name)
     return nil
 }
 
-func (adapter *AdapterFile) Async( b bool ) {
-    adapter.async = b
-}
-
-func (adapter *AdapterFile) IsAsync() bool {
-    return adapter.async
-}
-
-func (adapter *AdapterFile) Destroy() {
-    adapter.dwg.Add(1)
-    adapter.event <- ADAPTER_EVENT_DESTORY
-    adapter.dwg.Wait()
+func (adapter *AdapterFile) Sync() error {
+    err := adapter.FlushBuffer()
+    adapter.mutexWriter.Flush()
+    return err
 }
 
-func (adapter *AdapterFile) Flush() {
-    adapter.fwg.Add(1)
-    adapter.event <- ADAPTER_EVENT_FLUSH
-    adapter.fwg.Wait()
+func (adapter *AdapterFile) Close() error {
+    adapter.FlushBuffer()
+    adapter.mutexWriter.Close()
+    // 等待压缩完成
+    adapter.archiveLock.Lock()
+    adapter.archiveLock.Unlock()
+    return nil
 }
 
 func (adapter *AdapterFile) Run() {
     // 初始化
-    adapter.Initialize()
-    for{
-        select {
-            case message := <-adapter.channel:
-              err := adapter.write( message )
-              if err != nil {
-                  fmt.Println("Run:",err)
-              }
-              
-            case e := <-adapter.event:
-              switch e {
-                  case ADAPTER_EVENT_DESTORY:
-                    close(adapter.channel)
-                    close(adapter.event)
-                    adapter.mutexWriter.Close()
-                    adapter.dwg.Done()
-                    return
-                    
-                  case ADAPTER_EVENT_FLUSH:
-                    if len(adapter.channel) > 0 {
-                        adapter.write( <-adapter.channel )
-                    }
-                    adapter.mutexWriter.Flush()
-                    adapter.fwg.Done()
-                    return
-              }
-        }
+    adapter.lock.Lock()
+    if adapter.out == nil {
+        adapter.Initialize()
     }
+    adapter.lock.Unlock()
+    adapter.BaseAdapter.Run()
 }
 
 // 检查文件是否满足条件,进行日志分割
 func (adapter *AdapterFile) check( size int ) error {
-    if (adapter.size > adapter.MaxSize && adapter.size > 0) || 
-       (adapter.line > adapter.MaxLine && adapter.line > 0) ||
-       adapter.lastDay != time.Now().Day() {
+    state := RotationState{
+        Size     : adapter.size,
+        Lines    : adapter.line,
+        OpenedAt : adapter.openedAt,
+        Now      : time.Now(),
+    }
+    
+    if adapter.policy().ShouldRotate(state) {
         if err := adapter.rotate(); err != nil {
             fmt.Fprintf(os.Stderr, "AdapterFile(%q): %s\n", adapter.Filename, err)
             return err
@@ -223,89 +197,390 @@ func (adapter *AdapterFile) check( size int ) error {
     return nil
 }
 
+// 获取滚动策略
+func (adapter *AdapterFile) policy() RotationPolicy {
+    if adapter.Policy != nil {
+        return adapter.Policy
+    }
+    
+    return RotationAny{
+        &RotationDaily{},
+        &RotationSize{MaxSize: adapter.MaxSize},
+        &RotationLine{MaxLine: adapter.MaxLine},
+    }
+}
+
+// 生成滚动文件名
+func (adapter *AdapterFile) rotatedName( index int ) string {
+    return rotationName(adapter.NamePattern, adapter.TimeLayout, adapter.Filename, adapter.openedAt, index)
+}
+
 // 滚动日志分割
 func (adapter *AdapterFile) rotate() error {
+    if adapter.Shared {
+        // 等待锁之前写入缓存内容, 避免等待期间旧文件已被压缩
+        if err := adapter.FlushBuffer(); err != nil {
+            return err
+        }
+        
+        // 等待本进程上一次压缩完成, 压缩同样需要进程间锁
+        adapter.archiveLock.Lock()
+        adapter.archiveLock.Unlock()
+        
+        unlock, err := lockFile(adapter.lockName())
+        if err != nil {
+            return err
+        }
+        
+        defer unlock()
+        
+        // 其它进程已完成滚动, 重新打开即可
+        if !adapter.mutexWriter.SameFile(adapter.Filename) {
+            return adapter.reopen()
+        }
+    }
+    
+    // 缓存内容写入旧文件
+    if err := adapter.FlushBuffer(); err != nil {
+        return err
+    }
+    
     adapter.mutexWriter.Lock()
     defer adapter.mutexWriter.Unlock()
     adapter.mutexWriter.Close()
     _, e := os.Lstat(adapter.Filename)
     if e != nil {
-        return nil
+        // 文件已被外部移动, 无需滚动, 重新创建
+        return adapter.open()
     }
     
+    // 等待上一次压缩完成, 由压缩goroutine释放
+    adapter.archiveLock.Lock()
     for n := adapter.Rotation; n > 0 ; n -- {
-        fname  := adapter.Filename + fmt.Sprintf(".%s.%04d", time.Now().Format("2006-01-02"), n)
-        _, err := os.Lstat(fname)
-        if err != nil  {
+        fname, exists := adapter.rotatedFile(n)
+        if !exists {
             continue
         }
         
         if n >= adapter.Rotation {
-           err = os.Remove(fname)
+           err := os.Remove(fname)
            if err != nil {
+               adapter.archiveLock.Unlock()
                return err
            }
            continue
         } 
         
-        tname := adapter.Filename + fmt.Sprintf(".%s.%04d", time.Now().Format("2006-01-02"), n + 1)
-        err = os.Rename(fname, tname)
+        tname := adapter.rotatedName(n + 1) + strings.TrimPrefix(fname, adapter.rotatedName(n))
+        err := os.Rename(fname, tname)
         if err != nil {
+            adapter.archiveLock.Unlock()
             return err
         }
     }
     
     
-    tname := adapter.Filename + fmt.Sprintf(".%s.%04d", time.Now().Format("2006-01-02"), 1)
+    tname := adapter.rotatedName(1)
     err := os.Rename(adapter.Filename, tname)
     if err != nil {
+        adapter.archiveLock.Unlock()
         return err
     }
     
-    go adapter.deleteExpiredLog()
+    go adapter.archive(tname)
+    
     adapter.Initialize()
     return nil
 }
 
-// 删除过期日志
-func (adapter *AdapterFile) deleteExpiredLog() {
-    dir := filepath.Dir(adapter.Filename)
-    filepath.Walk(dir, func(path string, info os.FileInfo, err error) (returnErr error) {
-        defer func() {
-            if r := recover(); r != nil {
-                returnErr = fmt.Errorf("Unable to delete old log '%s', error: %+v", path, r)
-                fmt.Println(returnErr)
-            }
-        }()
+// 查找滚动文件, 优先返回已压缩的文件
+func (adapter *AdapterFile) rotatedFile( index int ) (string, bool) {
+    name := adapter.rotatedName(index)
+    if adapter.Compressor != nil {
+        if _, err := os.Lstat(name + adapter.Compressor.Extension()); err == nil {
+            return name + adapter.Compressor.Extension(), true
+        }
+    }
+    
+    if _, err := os.Lstat(name); err == nil {
+        return name, true
+    }
+    
+    return name, false
+}
+
+// 压缩滚动文件并清理旧日志, 完成后释放压缩锁
+func (adapter *AdapterFile) archive( name string ) {
+    defer adapter.archiveLock.Unlock()
+    if adapter.Shared {
+        unlock, err := lockFile(adapter.lockName())
+        if err != nil {
+            adapter.reportError(fmt.Errorf("AdapterFile(%q): lock %s: %v", adapter.Filename, adapter.lockName(), err))
+            return
+        }
         
-        if !info.IsDir() && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*adapter.MaxDays) {
-            if strings.HasPrefix(filepath.Base(path), filepath.Base(adapter.Filename)) {
-                os.Remove(path)
-            }
+        defer unlock()
+    }
+    
+    if adapter.Compressor != nil {
+        if adapter.Shared {
+            adapter.compressIdle(name)
+        } else if err := compressFile(adapter.Compressor, name); err != nil {
+            adapter.reportError(fmt.Errorf("AdapterFile(%q): compress %s: %v", adapter.Filename, name, err))
+        }
+    }
+    
+    adapter.deleteExpiredLog()
+}
+
+// 共享模式下压缩滚动文件
+// 其它进程在下一次写入前仍会向刚滚动的文件写入, 缓存内容也会在刷新时写入
+// 因此跳过刚滚动的文件latest, 只压缩超过等待时间未修改的文件, 其余留到之后的滚动
+func (adapter *AdapterFile) compressIdle( latest string ) {
+    files, err := adapter.rotatedFiles()
+    if err != nil {
+        adapter.reportError(fmt.Errorf("AdapterFile(%q): list rotated logs: %v", adapter.Filename, err))
+        return
+    }
+    
+    dir  := rotationDir(adapter.NamePattern, adapter.Filename)
+    ext  := adapter.Compressor.Extension()
+    idle := time.Now().Add(-adapter.sharedCompressDelay())
+    for _, info := range files {
+        if info.Name() == filepath.Base(latest) || strings.HasSuffix(info.Name(), ext) || info.ModTime().After(idle) {
+            continue
         }
+        
+        name := filepath.Join(dir, info.Name())
+        if err := compressFile(adapter.Compressor, name); err != nil && !os.IsNotExist(err) {
+            adapter.reportError(fmt.Errorf("AdapterFile(%q): compress %s: %v", adapter.Filename, name, err))
+        }
+    }
+}
+
+// 共享模式下滚动文件压缩前的等待时间, 为缓存刷新间隔的两倍
+func (adapter *AdapterFile) sharedCompressDelay() time.Duration {
+    interval := adapter.flushInterval
+    if interval <= 0 {
+        interval = DefaultFlushInterval
+    }
+    
+    return 2 * interval
+}
+
+// 获取共享模式的锁文件
+func (adapter *AdapterFile) lockName() string {
+    if adapter.LockFile != "" {
+        return adapter.LockFile
+    }
+    
+    return adapter.Filename + ".lock"
+}
+
+// 按保存天数, 数量以及总大小删除旧的滚动日志, 只处理符合本适配器滚动命名的文件
+// 从最旧的文件开始删除
+func (adapter *AdapterFile) deleteExpiredLog() {
+    files, err := adapter.rotatedFiles()
+    if err != nil {
+        adapter.reportError(fmt.Errorf("AdapterFile(%q): list rotated logs: %v", adapter.Filename, err))
         return
+    }
+    
+    // 最新的在前
+    sort.Slice(files, func(i, j int) bool {
+        return files[i].ModTime().After(files[j].ModTime())
     })
+    
+    expired := time.Now().Add(-time.Duration(adapter.MaxDays) * 24 * time.Hour)
+    var total int64
+    for i, info := range files {
+        total += info.Size()
+        remove := (adapter.MaxDays > 0 && info.ModTime().Before(expired)) ||
+            (adapter.MaxBackups > 0 && i >= adapter.MaxBackups) ||
+            (adapter.MaxTotalSize > 0 && total > adapter.MaxTotalSize)
+        if !remove {
+            continue
+        }
+        
+        name := filepath.Join(rotationDir(adapter.NamePattern, adapter.Filename), info.Name())
+        if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+            adapter.reportError(fmt.Errorf("AdapterFile(%q): unable to delete old log %q: %v", adapter.Filename, name, err))
+        }
+    }
+}
+
+// 获取本适配器的全部滚动文件
+func (adapter *AdapterFile) rotatedFiles() ([]os.FileInfo, error) {
+    ext := ""
+    if adapter.Compressor != nil {
+        ext = adapter.Compressor.Extension()
+    }
+    
+    re, err := rotationMatcher(adapter.NamePattern, adapter.TimeLayout, adapter.Filename, ext)
+    if err != nil {
+        return nil, err
+    }
+    
+    dir := rotationDir(adapter.NamePattern, adapter.Filename)
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+    
+    files := []os.FileInfo{}
+    for _, entry := range entries {
+        if !entry.Type().IsRegular() || !re.MatchString(entry.Name()) {
+            continue
+        }
+        
+        info, err := entry.Info()
+        if err != nil {
+            continue
+        }
+        
+        files = append(files, info)
+    }
+    
+    return files, nil
+}
+
+// 按BufferSize创建输出, 调用前缓存需已写入
+func (adapter *AdapterFile) setupWriter() {
+    if adapter.BufferSize <= 0 {
+        if adapter.buffer != nil || adapter.out == nil {
+            adapter.buffer = nil
+            adapter.out    = adapter.mutexWriter
+        }
+        return
+    }
+    
+    if adapter.buffer == nil || adapter.buffer.Size() != adapter.BufferSize {
+        adapter.buffer = bufio.NewWriterSize(adapter.mutexWriter, adapter.BufferSize)
+        adapter.out    = adapter.buffer
+    }
 }
 
 func (adapter *AdapterFile) Initialize() {
+    if err := adapter.open(); err != nil {
+        fmt.Fprintf(os.Stderr, "AdapterFile - Initialize-(%q): %s\n", adapter.Filename, err)
+    }
+}
+
+// 打开日志文件并统计当前大小以及行数
+func (adapter *AdapterFile) open() error {
+    adapter.setupWriter()
     size, err := adapter.mutexWriter.Open(adapter.Filename)
     if err != nil {
-        fmt.Fprintf(os.Stderr, "AdapterFile - Initialize-(%q): %s\n", adapter.Filename, err)
-        return
+        return err
     }
     
+    adapter.lastCheck = time.Now()
+    
     adapter.size     = size 
-    adapter.lastDay  = time.Now().Day()
+    adapter.openedAt = time.Now()
     adapter.line    = 0
     
     if adapter.size > 0 {
+        // 已有内容的文件以最后修改时间作为开始时间, 以便跨周期启动时正确滚动
+        if finfo, err := os.Stat(adapter.Filename); err == nil {
+            adapter.openedAt = finfo.ModTime()
+        }
+        
         num, err := adapter.lines()
          if err != nil {
-             return
+             return nil
          }
          
         adapter.line = num
     }
+    
+    return nil
+}
+
+// 重新打开日志文件, 用于logrotate等外部工具移动文件之后
+func (adapter *AdapterFile) Reopen() error {
+    adapter.lock.Lock()
+    defer adapter.lock.Unlock()
+    return adapter.reopen()
+}
+
+func (adapter *AdapterFile) reopen() error {
+    // 缓存内容写入旧文件
+    err := adapter.FlushBuffer()
+    adapter.mutexWriter.Lock()
+    defer adapter.mutexWriter.Unlock()
+    if oerr := adapter.open(); oerr != nil {
+        return oerr
+    }
+    
+    return err
+}
+
+// 检查日志文件是否已被外部移动或删除, 是则重新打开
+func (adapter *AdapterFile) checkMoved() error {
+    if adapter.Shared {
+        return adapter.checkShared()
+    }
+    
+    if adapter.ReopenCheckInterval <= 0 || time.Since(adapter.lastCheck) < adapter.ReopenCheckInterval {
+        return nil
+    }
+    
+    adapter.lastCheck = time.Now()
+    if adapter.mutexWriter.SameFile(adapter.Filename) {
+        return nil
+    }
+    
+    return adapter.reopen()
+}
+
+// 共享模式下每次写入前检查文件, 已被其它进程滚动时重新打开
+// 否则以文件实际大小作为当前大小, 使各进程按同一大小滚动
+func (adapter *AdapterFile) checkShared() error {
+    finfo, same := adapter.mutexWriter.Stat(adapter.Filename)
+    if !same {
+        return adapter.reopen()
+    }
+    
+    adapter.size = int(finfo.Size())
+    if adapter.buffer != nil {
+        adapter.size += adapter.buffer.Buffered()
+    }
+    
+    return nil
+}
+
+// 收到信号时重新打开日志文件, 未指定信号时使用SIGHUP
+// 返回停止函数
+func (adapter *AdapterFile) ReopenOnSignal( sig ...os.Signal ) func() {
+    if len(sig) == 0 {
+        sig = []os.Signal{syscall.SIGHUP}
+    }
+    
+    ch   := make(chan os.Signal, 1)
+    stop := make(chan struct{})
+    signal.Notify(ch, sig...)
+    go func() {
+        for {
+            select {
+                case <-stop:
+                  return
+                  
+                case <-ch:
+                  if err := adapter.Reopen(); err != nil {
+                      adapter.reportError(err)
+                  }
+            }
+        }
+    }()
+    
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            signal.Stop(ch)
+            close(stop)
+        })
+    }
 }
 
 func (adapter *AdapterFile) lines() (int, error) {
@@ -333,16 +608,9 @@ func (adapter *AdapterFile) lines() (int, error) {
 }
 
 func NewAdapterFile( channelLen int ) *AdapterFile {
-    adapter:= &AdapterFile{
-        channel : make(chan Message, channelLen),
-        event   : make(chan AdapterEvent),
-        formatter : new(FormatterText),
-        hooks   : make(map[string]Hook),
-        async   : true,
-    }
-    
+    adapter:= &AdapterFile{}
+    adapter.BaseAdapter = NewBaseAdapter(adapter, channelLen)
     adapter.mutexWriter = new(fileMutex)
-    adapter.out         = log.New(adapter.mutexWriter, "", log.Ldate|log.Ltime)
     
     // 默认配置
     adapter.Filename    = "log"
@@ -350,5 +618,6 @@ func NewAdapterFile( channelLen int ) *AdapterFile {
     adapter.MaxSize     = 1 << 28
     adapter.MaxDays     = 30
     adapter.Rotation    = 50
+    adapter.BufferSize  = 64 << 10
     return adapter
 }
\ No newline at end of file
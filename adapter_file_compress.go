@@ -0,0 +1,97 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 滚动日志压缩
+package logmo
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// 压缩接口, 可实现zstd等其它压缩方式
+type Compressor interface {
+	// 压缩文件扩展名, 如 ".gz"
+	Extension() string
+
+	// 创建压缩写入
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// gzip压缩
+type CompressorGzip struct {
+	// 压缩级别, 0 为默认级别
+	Level int
+}
+
+func (c *CompressorGzip) Extension() string {
+	return ".gz"
+}
+
+func (c *CompressorGzip) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.Level == 0 {
+		return gzip.NewWriter(w), nil
+	}
+
+	return gzip.NewWriterLevel(w, c.Level)
+}
+
+// 压缩文件, 成功后删除源文件
+// 先写入临时文件, 完成后再改名, 避免留下不完整的压缩文件
+func compressFile(c Compressor, src string) error {
+	dst := src + c.Extension()
+	tmp := dst + ".tmp"
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.NewWriter(out)
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if _, err = io.Copy(w, in); err == nil {
+		err = w.Close()
+	}
+
+	if err == nil {
+		err = out.Sync()
+	}
+
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
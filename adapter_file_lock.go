@@ -0,0 +1,53 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+// 多进程共享文件日志时的进程间锁
+package logmo
+
+import (
+	"os"
+	"syscall"
+)
+
+// 以flock独占锁定name, 返回解锁函数
+func lockFile(name string) (func() error, error) {
+	fd, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(fd.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	return func() error {
+		err := syscall.Flock(int(fd.Fd()), syscall.LOCK_UN)
+		if cerr := fd.Close(); err == nil {
+			err = cerr
+		}
+
+		return err
+	}, nil
+}
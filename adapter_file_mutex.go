@@ -53,6 +53,29 @@ func (mutex *fileMutex) Open( file string ) (int, error) {
     return int(finfo.Size()), nil
 }
 
+// 当前打开的文件是否仍为file指向的文件
+func (mutex *fileMutex) SameFile( file string ) bool {
+    _, same := mutex.Stat(file)
+    return same
+}
+
+// 获取file的文件信息, 并返回当前打开的文件是否仍为file指向的文件
+func (mutex *fileMutex) Stat( file string ) (os.FileInfo, bool) {
+    mutex.Lock()
+    defer mutex.Unlock()
+    finfo, err := os.Stat(file)
+    if err != nil || mutex.fd == nil {
+        return nil, false
+    }
+    
+    current, err := mutex.fd.Stat()
+    if err != nil {
+        return finfo, false
+    }
+    
+    return finfo, os.SameFile(current, finfo)
+}
+
 func (mutex *fileMutex) Close() {
     mutex.fd.Close()
 }
@@ -0,0 +1,246 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 文件日志滚动策略
+package logmo
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 默认滚动文件名模板
+const DefaultRotationNamePattern = "{name}.{time}.{index}"
+
+// 默认滚动文件名时间格式
+const DefaultRotationTimeLayout = "2006-01-02"
+
+// 当前日志文件状态
+type RotationState struct {
+	// 当前文件大小
+	Size int
+
+	// 当前文件行数
+	Lines int
+
+	// 当前文件开始写入时间
+	OpenedAt time.Time
+
+	// 当前时间
+	Now time.Time
+}
+
+// 滚动策略, 每次写入前检查是否需要滚动
+type RotationPolicy interface {
+	ShouldRotate(state RotationState) bool
+}
+
+// 按小时滚动
+type RotationHourly struct{}
+
+func (policy *RotationHourly) ShouldRotate(state RotationState) bool {
+	return (&RotationDaily{}).ShouldRotate(state) || state.OpenedAt.Hour() != state.Now.Hour()
+}
+
+// 按天滚动
+type RotationDaily struct{}
+
+func (policy *RotationDaily) ShouldRotate(state RotationState) bool {
+	y1, m1, d1 := state.OpenedAt.Date()
+	y2, m2, d2 := state.Now.Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+// 按周滚动, 每周一开始新文件
+type RotationWeekly struct{}
+
+func (policy *RotationWeekly) ShouldRotate(state RotationState) bool {
+	return !weekStart(state.OpenedAt).Equal(weekStart(state.Now))
+}
+
+// 获取所在周周一零点
+func weekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+}
+
+// 按文件大小滚动, MaxSize 小于等于0时不滚动
+type RotationSize struct {
+	MaxSize int
+}
+
+func (policy *RotationSize) ShouldRotate(state RotationState) bool {
+	return policy.MaxSize > 0 && state.Size > policy.MaxSize
+}
+
+// 按文件行数滚动, MaxLine 小于等于0时不滚动
+type RotationLine struct {
+	MaxLine int
+}
+
+func (policy *RotationLine) ShouldRotate(state RotationState) bool {
+	return policy.MaxLine > 0 && state.Lines > policy.MaxLine
+}
+
+// 从不滚动, 保持单个文件持续增长
+type RotationNever struct{}
+
+func (policy *RotationNever) ShouldRotate(state RotationState) bool {
+	return false
+}
+
+// 组合策略, 任一策略满足即滚动
+type RotationAny []RotationPolicy
+
+func (policies RotationAny) ShouldRotate(state RotationState) bool {
+	for _, policy := range policies {
+		if policy.ShouldRotate(state) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 生成滚动文件名
+// 模板支持 {name} 原文件名, {time} 按时间格式输出的时间, {index} 滚动序号
+func rotationName(pattern, layout, name string, t time.Time, index int) string {
+	if pattern == "" {
+		pattern = DefaultRotationNamePattern
+	}
+
+	if layout == "" {
+		layout = DefaultRotationTimeLayout
+	}
+
+	return strings.NewReplacer(
+		"{name}", name,
+		"{time}", t.Format(layout),
+		"{index}", fmt.Sprintf("%04d", index),
+	).Replace(pattern)
+}
+
+// 滚动文件所在目录
+func rotationDir(pattern, name string) string {
+	if pattern == "" {
+		pattern = DefaultRotationNamePattern
+	}
+
+	return filepath.Dir(strings.Replace(pattern, "{name}", name, -1))
+}
+
+// 生成匹配滚动文件名(不含目录)的正则, ext 为压缩扩展名, 可为空
+func rotationMatcher(pattern, layout, name, ext string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = DefaultRotationNamePattern
+	}
+
+	if layout == "" {
+		layout = DefaultRotationTimeLayout
+	}
+
+	base := filepath.Base(strings.Replace(pattern, "{name}", name, -1))
+	expr := regexp.QuoteMeta(base)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{time}"), "(?:"+layoutExpr(layout)+")", -1)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{index}"), `\d{4,}`, -1)
+	if ext != "" {
+		expr += "(?:" + regexp.QuoteMeta(ext) + ")?"
+	}
+
+	return regexp.Compile("^" + expr + "$")
+}
+
+// 时间格式元素对应的正则, 按长度从长到短匹配
+var layoutElements = []struct {
+	element string
+	expr    string
+}{
+	{"-07:00:00", `[+-]\d{2}:\d{2}:\d{2}`},
+	{"Z07:00:00", `(?:Z|[+-]\d{2}:\d{2}:\d{2})`},
+	{"January", `[A-Za-z]+`},
+	{"-07:00", `[+-]\d{2}:\d{2}`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`},
+	{"Monday", `[A-Za-z]+`},
+	{"-0700", `[+-]\d{4}`},
+	{"Z0700", `(?:Z|[+-]\d{4})`},
+	{"2006", `\d{4}`},
+	{"Jan", `[A-Za-z]{3}`},
+	{"Mon", `[A-Za-z]{3}`},
+	{"MST", `(?:[A-Za-z]{3,5}|[+-]\d{2,4})`},
+	{"-07", `[+-]\d{2}`},
+	{"Z07", `(?:Z|[+-]\d{2})`},
+	{"002", `\d{3}`},
+	{"__2", `[ \d]{2}\d`},
+	{"_2", `[ \d]\d`},
+	{"01", `\d{2}`},
+	{"02", `\d{2}`},
+	{"03", `\d{2}`},
+	{"04", `\d{2}`},
+	{"05", `\d{2}`},
+	{"06", `\d{2}`},
+	{"15", `\d{2}`},
+	{"PM", `[AP]M`},
+	{"pm", `[ap]m`},
+	{"1", `\d{1,2}`},
+	{"2", `\d{1,2}`},
+	{"3", `\d{1,2}`},
+	{"4", `\d{1,2}`},
+	{"5", `\d{1,2}`},
+}
+
+// 按时间格式生成匹配时间的正则
+func layoutExpr(layout string) string {
+	var expr strings.Builder
+	for i := 0; i < len(layout); {
+		// 秒的小数部分 .000 或 .999
+		if c := layout[i]; (c == '.' || c == ',') && i+1 < len(layout) && (layout[i+1] == '0' || layout[i+1] == '9') {
+			j := i + 1
+			for j < len(layout) && layout[j] == layout[i+1] {
+				j++
+			}
+
+			if j == len(layout) || layout[j] < '0' || layout[j] > '9' {
+				if layout[i+1] == '0' {
+					expr.WriteString(fmt.Sprintf(`[.,]\d{%d}`, j-i-1))
+				} else {
+					expr.WriteString(`(?:[.,]\d+)?`)
+				}
+
+				i = j
+				continue
+			}
+		}
+
+		matched := false
+		for _, e := range layoutElements {
+			if strings.HasPrefix(layout[i:], e.element) {
+				expr.WriteString(e.expr)
+				i += len(e.element)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			expr.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+
+	return expr.String()
+}
@@ -16,6 +16,15 @@
 package logmo
 
 import(
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
     "testing"
     "time"
 )
@@ -58,4 +67,477 @@ func createLog() *Logger{
     go fw.Run()
     log.AddAdapter("asyncfile", fw)
     return log
-}
\ No newline at end of file
+}
+
+func TestFileRotationPolicy(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.Policy = &RotationLine{MaxLine: 2}
+	fw.NamePattern = "{name}-{time}-{index}.log"
+	fw.TimeLayout = "20060102"
+	fw.Rotation = 3
+	fw.Initialize()
+	defer fw.Close()
+
+	m := &DefaultMessage{Level: INFO, Message: "specific language governing permissions", Time: time.Now()}
+	for i := 0; i < 7; i++ {
+		if err := fw.SyncWrite(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	day := time.Now().Format("20060102")
+	for _, index := range []string{"0001", "0002"} {
+		if _, err := os.Stat(fw.Filename + "-" + day + "-" + index + ".log"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRotationPolicies(t *testing.T) {
+	opened := time.Date(2015, 6, 7, 10, 59, 0, 0, time.Local) // Sunday
+	cases := []struct {
+		policy RotationPolicy
+		now    time.Time
+		expect bool
+	}{
+		{&RotationHourly{}, opened.Add(time.Minute), true},
+		{&RotationHourly{}, opened.Add(-time.Minute), false},
+		{&RotationDaily{}, opened.Add(time.Hour), false},
+		{&RotationDaily{}, opened.Add(14 * time.Hour), true},
+		{&RotationWeekly{}, opened.Add(-24 * time.Hour), false},
+		{&RotationWeekly{}, opened.Add(14 * time.Hour), true},
+		{&RotationNever{}, opened.Add(1000 * time.Hour), false},
+	}
+
+	for i, c := range cases {
+		state := RotationState{OpenedAt: opened, Now: c.now}
+		if c.policy.ShouldRotate(state) != c.expect {
+			t.Fatalf("case %d: expect %v", i, c.expect)
+		}
+	}
+}
+
+func TestFileRotationCompress(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.Policy = &RotationLine{MaxLine: 1}
+	fw.Compressor = &CompressorGzip{}
+	fw.Rotation = 5
+	fw.Initialize()
+	defer fw.Close()
+
+	m := &DefaultMessage{Level: INFO, Message: "specific language governing permissions", Time: time.Now()}
+	for i := 0; i < 6; i++ {
+		if err := fw.SyncWrite(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 等待压缩完成
+	fw.archiveLock.Lock()
+	fw.archiveLock.Unlock()
+
+	for _, index := range []int{1, 2} {
+		name := fw.rotatedName(index)
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("%s must be removed after compression", name)
+		}
+
+		fd, err := os.Open(name + ".gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := gzip.NewReader(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := io.ReadAll(r)
+		fd.Close()
+		if err != nil || !strings.Contains(string(b), m.Message) {
+			t.Fatalf("unexpected content %q: %v", b, err)
+		}
+	}
+}
+
+func TestFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.Compressor = &CompressorGzip{}
+	fw.MaxDays = 0
+	fw.MaxBackups = 2
+
+	names := []string{
+		"app.log.2015-06-01.0002",
+		"app.log.2015-06-01.0001.gz",
+		"app.log.2015-06-02.0001",
+		"app.log.2015-06-03.0001.gz",
+	}
+
+	now := time.Now()
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("specific language governing permissions"), 0660); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, now, now.Add(time.Duration(i-len(names))*time.Hour))
+	}
+
+	for _, name := range []string{"app.log", "app.log.conf", "app.log.2015-06-01.0001.zip"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0660)
+		os.Chtimes(filepath.Join(dir, name), now, now.Add(-1000*time.Hour))
+	}
+
+	fw.deleteExpiredLog()
+	entries, _ := os.ReadDir(dir)
+	left := []string{}
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+
+	expected := "app.log app.log.2015-06-01.0001.zip app.log.2015-06-02.0001 app.log.2015-06-03.0001.gz app.log.conf"
+	if strings.Join(left, " ") != expected {
+		t.Fatalf("unexpected files: %v", left)
+	}
+
+	fw.MaxBackups = 0
+	fw.MaxTotalSize = 50
+	fw.deleteExpiredLog()
+	if _, err := os.Stat(filepath.Join(dir, "app.log.2015-06-02.0001")); !os.IsNotExist(err) {
+		t.Fatal("oldest segment must be removed when total size exceeded")
+	}
+}
+
+func TestFileRetentionSiblings(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.MaxDays = 0
+	fw.MaxBackups = 1
+
+	now := time.Now()
+	names := []string{
+		// 其它适配器(app.log.2)的滚动文件以及无关文件
+		"app.log.2.2015-06-01.0001",
+		"app.log.old.1",
+		"app.log.2015-06-01.1",
+		"app.log.2015-6-01.0001",
+		// 本适配器的滚动文件
+		"app.log.2015-06-01.0002",
+		"app.log.2015-06-02.0001",
+	}
+
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, nil, 0660)
+		os.Chtimes(path, now, now.Add(time.Duration(i-len(names))*time.Hour))
+	}
+
+	fw.deleteExpiredLog()
+	entries, _ := os.ReadDir(dir)
+	left := []string{}
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+
+	expected := "app.log.2.2015-06-01.0001 app.log.2015-06-01.1 app.log.2015-06-02.0001 app.log.2015-6-01.0001 app.log.old.1"
+	if strings.Join(left, " ") != expected {
+		t.Fatalf("unexpected files: %v", left)
+	}
+}
+
+func TestRotationMatcher(t *testing.T) {
+	for _, test := range []struct {
+		layout string
+		name   string
+		match  bool
+	}{
+		{"2006-01-02T15", "app.log.2015-06-01T09.0001", true},
+		{"2006-01-02T15", "app.log.2015-06-01.0001", false},
+		{"20060102", "app.log.20150601.0012", true},
+		{"20060102", "app.log.2015060.0012", false},
+		{"Jan _2 15:04:05.000", "app.log.Jun  1 09:10:11.123.0001", true},
+		{"2006-01-02 15:04:05.999999999 -0700", "app.log.2015-06-01 09:10:11.5 +0800.0001", true},
+		{"2006-01-02 15:04:05.999999999 -0700", "app.log.2015-06-01 09:10:11 +0800.0001", true},
+	} {
+		re, err := rotationMatcher("", test.layout, "app.log", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if re.MatchString(test.name) != test.match {
+			t.Fatalf("%s: %q match %v expected %v", test.layout, test.name, !test.match, test.match)
+		}
+	}
+}
+
+func TestFileBuffered(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(1000)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.BufferSize = 1 << 20
+	fw.SetFlushInterval(time.Hour)
+	go fw.Run()
+	defer fw.Destroy()
+
+	m := &DefaultMessage{Level: INFO, Message: "specific language governing permissions", Time: time.Now()}
+	for i := 0; i < 500; i++ {
+		fw.AsyncWrite(m)
+	}
+
+	fw.Flush()
+	b, err := os.ReadFile(fw.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(string(b), "\n"); n != 500 {
+		t.Fatalf("unexpected lines: %d", n)
+	}
+
+	// 缓存中的信息在刷新前不写入文件
+	fw.lock.Lock()
+	fw.WriteMessage(m, []byte(m.Message))
+	info, _ := os.Stat(fw.Filename)
+	fw.FlushBuffer()
+	fw.lock.Unlock()
+	if info.Size() != int64(len(b)) {
+		t.Fatal("buffered message must not reach the file before flush")
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.Policy = &RotationNever{}
+	fw.ReopenCheckInterval = time.Nanosecond
+	fw.Initialize()
+	defer fw.Close()
+
+	m := &DefaultMessage{Level: INFO, Message: "first", Time: time.Now()}
+	fw.SyncWrite(m)
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(fw.Filename, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Message = "second"
+	fw.SyncWrite(m)
+	os.Rename(fw.Filename, moved+".1")
+	fw.ReopenCheckInterval = 0
+	if err := fw.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Message = "third"
+	fw.SyncWrite(m)
+	for name, expected := range map[string]string{moved: "first", moved + ".1": "second", fw.Filename: "third"} {
+		b, _ := os.ReadFile(name)
+		if !strings.HasSuffix(string(b), expected+"\n") || strings.Count(string(b), "\n") != 1 {
+			t.Fatalf("%s: unexpected content %q", name, b)
+		}
+	}
+}
+
+func TestFileShared(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	n := 300
+
+	var wg sync.WaitGroup
+	for w := 0; w < 3; w++ {
+		fw := NewAdapterFile(10)
+		fw.Filename = filename
+		fw.Shared = true
+		fw.Rotation = 1000
+		fw.Policy = &RotationSize{MaxSize: 4096}
+		fw.Initialize()
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer fw.Close()
+			for i := 0; i < n; i++ {
+				fw.SyncWrite(&DefaultMessage{Level: INFO, Message: fmt.Sprintf("worker-%d line-%04d", w, i), Time: time.Now()})
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Name() == "app.log.lock" {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+			i := strings.Index(line, "worker-")
+			if i < 0 || seen[line[i:]] {
+				t.Fatalf("%s: unexpected line %q", entry.Name(), line)
+			}
+
+			seen[line[i:]] = true
+		}
+	}
+
+	if len(seen) != 3*n {
+		t.Fatalf("expected %d lines, got %d", 3*n, len(seen))
+	}
+
+	if len(entries) < 3 {
+		t.Fatalf("expected rotated files, got %d entries", len(entries))
+	}
+}
+
+func TestFileSharedCompress(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	n := 2000
+
+	var wg sync.WaitGroup
+	for w := 0; w < 3; w++ {
+		fw := NewAdapterFile(n)
+		fw.Filename = filename
+		fw.Shared = true
+		fw.Rotation = 1000
+		fw.Compressor = &CompressorGzip{}
+		fw.Policy = &RotationSize{MaxSize: 8192}
+		fw.SetFlushInterval(10 * time.Millisecond)
+		fw.Initialize()
+		go fw.Run()
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				fw.AsyncWrite(&DefaultMessage{Level: INFO, Message: fmt.Sprintf("worker-%d line-%04d", w, i), Time: time.Now()})
+				if i%50 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			if _, err := fw.Shutdown(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	compressed := 0
+	for _, entry := range entries {
+		if entry.Name() == "app.log.lock" {
+			continue
+		}
+
+		fd, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r io.Reader = fd
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			compressed++
+			if r, err = gzip.NewReader(fd); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		b, err := io.ReadAll(r)
+		fd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+			if i := strings.Index(line, "worker-"); i >= 0 {
+				seen[line[i:]] = true
+			}
+		}
+	}
+
+	if len(seen) != 3*n {
+		t.Fatalf("expected %d lines, got %d", 3*n, len(seen))
+	}
+
+	if compressed == 0 {
+		t.Fatal("expected compressed rotated files")
+	}
+}
+
+func TestFileJSON(t *testing.T) {
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(t.TempDir(), "app.log")
+	fw.SetFormatter(new(FormatterJSON))
+	fw.Initialize()
+	for i := 0; i < 3; i++ {
+		m := &DefaultMessage{Level: INFO, Message: fmt.Sprintf("line %d", i), Fields: Fields{"n": i}, Time: time.Now()}
+		if err := fw.SyncWrite(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fw.Close()
+	b, _ := os.ReadFile(fw.Filename)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected content %q", b)
+	}
+
+	for i, line := range lines {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("line %d is not json: %q: %v", i, line, err)
+		}
+	}
+}
+
+func TestFileMovedRotate(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewAdapterFile(10)
+	fw.Filename = filepath.Join(dir, "app.log")
+	fw.Policy = &RotationLine{MaxLine: 2}
+	fw.Initialize()
+	defer fw.Close()
+
+	moved := filepath.Join(dir, "moved.log")
+	for i, text := range []string{"first", "second", "third", "fourth"} {
+		if i == 1 {
+			if err := os.Rename(fw.Filename, moved); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := fw.SyncWrite(&DefaultMessage{Level: INFO, Message: text, Time: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, expected := range map[string]string{moved: "third", fw.Filename: "fourth"} {
+		b, _ := os.ReadFile(name)
+		if !strings.HasSuffix(string(b), expected+"\n") {
+			t.Fatalf("%s: unexpected content %q", name, b)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// 转发到slog.Handler
+package logmo
+
+import (
+	"context"
+	"log/slog"
+)
+
+// 将信息转发到任意slog.Handler, 附加字段以及附加数据转为slog属性
+type AdapterSlog struct {
+	*BaseAdapter
+
+	handler slog.Handler
+
+	// 是否附加调用位置属性 slog.SourceKey
+	AddSource bool
+}
+
+func (adapter *AdapterSlog) WriteMessage(message Message, b []byte) error {
+	level := SlogLevel(message.GetLevel())
+	if !adapter.handler.Enabled(context.Background(), level) {
+		return nil
+	}
+
+	r := slog.NewRecord(message.GetTime(), level, message.GetMessage(), 0)
+	fields := message.GetFields()
+	for _, key := range fields.Keys() {
+		r.AddAttrs(slog.Any(key, fields[key]))
+	}
+
+	if data := message.GetData(); data != nil {
+		r.AddAttrs(slog.Any("data", data))
+	}
+
+	if adapter.AddSource && message.GetFile() != "" {
+		r.AddAttrs(slog.Any(slog.SourceKey, &slog.Source{File: message.GetFile(), Line: message.GetLine()}))
+	}
+
+	return adapter.handler.Handle(context.Background(), r)
+}
+
+func (adapter *AdapterSlog) Sync() error {
+	return nil
+}
+
+func (adapter *AdapterSlog) Close() error {
+	return nil
+}
+
+// slog.Handler自行格式化, 不需要格式化输出
+type formatterNone struct{}
+
+func (formatterNone) Format(message Message) ([]byte, error) {
+	return nil, nil
+}
+
+// 创建转发到handler的适配器
+func NewAdapterSlog(channelLen int, handler slog.Handler) *AdapterSlog {
+	adapter := &AdapterSlog{handler: handler}
+	adapter.BaseAdapter = NewBaseAdapter(adapter, channelLen)
+	adapter.SetFormatter(formatterNone{})
+	return adapter
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// slog适配器测试
+package logmo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestAdapterSlog(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := NewAdapterSlog(0, slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	adapter.AddSource = true
+
+	log := newLogger()
+	log.AddAdapter("slog", adapter)
+	log.With("user", 7).SyncNotice("login %s", "ok")
+	log.SyncDebug("filtered")
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+
+	if v["msg"] != "login ok" || v["level"] != "INFO+2" || v["user"] != float64(7) {
+		t.Fatalf("unexpected record %s", buf.String())
+	}
+
+	if source, ok := v["source"].(map[string]interface{}); !ok || source["file"] != "adapter_slog_test.go" {
+		t.Fatalf("unexpected source %s", buf.String())
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// syslog日志支持
+package logmo
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslog格式
+type SyslogFormat byte
+
+const (
+	SYSLOG_RFC5424 SyslogFormat = iota
+	SYSLOG_RFC3164
+)
+
+// syslog设施
+type SyslogFacility byte
+
+const (
+	FACILITY_KERN SyslogFacility = iota
+	FACILITY_USER
+	FACILITY_MAIL
+	FACILITY_DAEMON
+	FACILITY_AUTH
+	FACILITY_SYSLOG
+	FACILITY_LPR
+	FACILITY_NEWS
+	FACILITY_UUCP
+	FACILITY_CRON
+	FACILITY_AUTHPRIV
+	FACILITY_FTP
+	_
+	_
+	_
+	_
+	FACILITY_LOCAL0
+	FACILITY_LOCAL1
+	FACILITY_LOCAL2
+	FACILITY_LOCAL3
+	FACILITY_LOCAL4
+	FACILITY_LOCAL5
+	FACILITY_LOCAL6
+	FACILITY_LOCAL7
+)
+
+// 本地syslog地址
+var syslogLocalAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslog适配器, 格式化结果作为syslog的MSG部分
+type AdapterSyslog struct {
+	*BaseAdapter
+
+	// 连接
+	conn net.Conn
+
+	// 网络类型 udp, tcp, tls, unix, unixgram, 为空时连接本地syslog
+	Network string
+
+	// 服务器地址
+	Addr string
+
+	// TLS配置, Network为tls时使用
+	TLSConfig *tls.Config
+
+	// 连接超时
+	Timeout time.Duration
+
+	// 格式
+	Format SyslogFormat
+
+	// 设施
+	Facility SyslogFacility
+
+	// 主机名
+	Hostname string
+
+	// 应用名
+	AppName string
+
+	// 结构化数据ID, 附加字段作为该SD-ELEMENT的参数输出, 仅RFC5424
+	StructuredDataID string
+}
+
+func (adapter *AdapterSyslog) WriteMessage(message Message, msg []byte) error {
+	if adapter.conn == nil {
+		if err := adapter.connect(); err != nil {
+			return err
+		}
+	}
+
+	_, err := adapter.conn.Write(adapter.packet(message, msg))
+	if err == nil {
+		return nil
+	}
+
+	// 连接断开后重连一次
+	adapter.conn.Close()
+	adapter.conn = nil
+	if err := adapter.connect(); err != nil {
+		return err
+	}
+
+	_, err = adapter.conn.Write(adapter.packet(message, msg))
+	return err
+}
+
+// 建立连接
+func (adapter *AdapterSyslog) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	dialer := &net.Dialer{Timeout: adapter.Timeout}
+	switch adapter.Network {
+	case "":
+		err = errors.New("logmo: no local syslog socket found")
+		for _, addr := range syslogLocalAddrs {
+			for _, network := range []string{"unixgram", "unix"} {
+				if conn, err = dialer.Dial(network, addr); err == nil {
+					adapter.conn = conn
+					return nil
+				}
+			}
+		}
+		return err
+
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", adapter.Addr, adapter.TLSConfig)
+
+	default:
+		conn, err = dialer.Dial(adapter.Network, adapter.Addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	adapter.conn = conn
+	return nil
+}
+
+// 是否为流式连接, 流式连接需要分帧
+func (adapter *AdapterSyslog) stream() bool {
+	if adapter.conn == nil {
+		return false
+	}
+
+	switch adapter.conn.LocalAddr().Network() {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	}
+
+	return false
+}
+
+// 生成syslog报文
+func (adapter *AdapterSyslog) packet(message Message, msg []byte) []byte {
+	level := message.GetLevel()
+	if level > DEBUG {
+		level = DEBUG
+	}
+
+	pri := int(adapter.Facility)*8 + int(level)
+	var buf bytes.Buffer
+	if adapter.Format == SYSLOG_RFC3164 {
+		fmt.Fprintf(&buf, "<%d>%s %s %s[%d]: ",
+			pri,
+			message.GetTime().Format(time.Stamp),
+			syslogValue(adapter.Hostname, 255),
+			syslogValue(adapter.AppName, 32),
+			message.GetPID(),
+		)
+	} else {
+		fmt.Fprintf(&buf, "<%d>1 %s %s %s %d - %s ",
+			pri,
+			message.GetTime().Format("2006-01-02T15:04:05.000000Z07:00"),
+			syslogValue(adapter.Hostname, 255),
+			syslogValue(adapter.AppName, 48),
+			message.GetPID(),
+			adapter.structuredData(message.GetFields()),
+		)
+	}
+
+	buf.Write(msg)
+	if !adapter.stream() {
+		return buf.Bytes()
+	}
+
+	// RFC5424 使用octet-counting分帧, RFC3164 使用换行分帧
+	if adapter.Format == SYSLOG_RFC3164 {
+		buf.WriteByte('\n')
+		return buf.Bytes()
+	}
+
+	return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+}
+
+// 生成结构化数据
+func (adapter *AdapterSyslog) structuredData(fields Fields) string {
+	if len(fields) == 0 || adapter.StructuredDataID == "" {
+		return "-"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.WriteString(syslogName(adapter.StructuredDataID))
+	for _, k := range fields.Keys() {
+		var value string
+		switch v := fields[k].(type) {
+		case string:
+			value = v
+		case error:
+			value = v.Error()
+		default:
+			value = fmt.Sprint(v)
+		}
+
+		buf.WriteByte(' ')
+		buf.WriteString(syslogName(k))
+		buf.WriteString(`="`)
+		buf.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value))
+		buf.WriteByte('"')
+	}
+
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// 头部字段, 空值使用 - , 去除空白并限制长度
+func syslogValue(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+
+	if s == "" {
+		return "-"
+	}
+
+	if len(s) > max {
+		s = s[:max]
+	}
+
+	return s
+}
+
+// SD-NAME 只允许可打印ASCII, 不含 = ] " 及空格, 最长32
+func syslogName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+
+	if len(s) > 32 {
+		s = s[:32]
+	}
+
+	return s
+}
+
+func (adapter *AdapterSyslog) Sync() error {
+	return nil
+}
+
+func (adapter *AdapterSyslog) Close() error {
+	if adapter.conn == nil {
+		return nil
+	}
+
+	err := adapter.conn.Close()
+	adapter.conn = nil
+	return err
+}
+
+// 创建syslog适配器, network为空时连接本地syslog
+func NewAdapterSyslog(channelLen int, network, addr string) *AdapterSyslog {
+	hostname, _ := os.Hostname()
+	adapter := &AdapterSyslog{
+		Network:          network,
+		Addr:             addr,
+		Timeout:          5 * time.Second,
+		Facility:         FACILITY_USER,
+		Hostname:         hostname,
+		AppName:          filepath.Base(os.Args[0]),
+		StructuredDataID: "logmo@32473",
+	}
+
+	adapter.BaseAdapter = NewBaseAdapter(adapter, channelLen)
+	adapter.SetFormatter(&FormatterPattern{Layout: "%msg"})
+	return adapter
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// syslog日志测试
+package logmo
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	sl := NewAdapterSyslog(10, "udp", conn.LocalAddr().String())
+	sl.Hostname = "host"
+	sl.AppName = "app"
+	sl.Facility = FACILITY_LOCAL0
+	m := newTestMessage()
+	if err := sl.SyncWrite(m); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<132>1 2015-06-01T08:30:00.000000Z host app 100 - [logmo@32473 err="disk full" request_id="r-1"] specific language governing permissions`
+	if string(buf[:n]) != expected {
+		t.Fatalf("unexpected packet:\n%s\n%s", buf[:n], expected)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	sl := NewAdapterSyslog(10, "tcp", ln.Addr().String())
+	sl.Format = SYSLOG_RFC3164
+	sl.Hostname = "host"
+	sl.AppName = "app"
+	if err := sl.SyncWrite(newTestMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case line := <-lines:
+		if line != "<12>Jun  1 08:30:00 host app[100]: specific language governing permissions\n" {
+			t.Fatalf("unexpected line: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not received")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// io.Writer日志支持
+package logmo
+
+import (
+	"io"
+	"os"
+)
+
+// 写入任意io.Writer, 如bytes.Buffer, 管道或网络连接
+type AdapterWriter struct {
+	*BaseAdapter
+
+	out io.Writer
+
+	// 销毁时若out实现io.Closer则一并关闭, 默认不关闭
+	CloseOnDestroy bool
+}
+
+func (adapter *AdapterWriter) WriteMessage(message Message, b []byte) error {
+	_, err := adapter.out.Write(append(b[:len(b):len(b)], '\n'))
+	return err
+}
+
+// 写入器支持 Sync() error 或 Flush() error 时调用
+// *os.File只同步普通文件, 标准输出, 管道等不支持同步
+func (adapter *AdapterWriter) Sync() error {
+	switch w := adapter.out.(type) {
+	case *os.File:
+		if info, err := w.Stat(); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return w.Sync()
+	case interface{ Sync() error }:
+		return w.Sync()
+	case interface{ Flush() error }:
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// 设置CloseOnDestroy并且写入器支持 io.Closer 时关闭
+func (adapter *AdapterWriter) Close() error {
+	if !adapter.CloseOnDestroy {
+		return nil
+	}
+
+	if c, ok := adapter.out.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// 创建写入io.Writer的适配器, 销毁时不关闭out, 需要关闭时设置CloseOnDestroy
+func NewAdapterWriter(channelLen int, out io.Writer) *AdapterWriter {
+	adapter := &AdapterWriter{out: out}
+	adapter.BaseAdapter = NewBaseAdapter(adapter, channelLen)
+	return adapter
+}
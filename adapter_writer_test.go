@@ -0,0 +1,98 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// io.Writer适配器测试
+package logmo
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// 记录Sync以及Close调用的写入器
+type closeBuffer struct {
+	bytes.Buffer
+	syncs  int
+	closed bool
+}
+
+func (b *closeBuffer) Sync() error {
+	b.syncs++
+	return nil
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestAdapterWriter(t *testing.T) {
+	out := new(closeBuffer)
+	adapter := NewAdapterWriter(100, out)
+	adapter.CloseOnDestroy = true
+	adapter.SetFormatter(&FormatterPattern{Layout: "%prefix %msg"})
+	adapter.AddHook("level", &HookLevel{NOTICE})
+
+	log := newLogger()
+	log.AddAdapter("writer", adapter)
+	go adapter.Run()
+	for i := 0; i < 10; i++ {
+		log.Notice("line %d", i)
+		log.Info("filtered")
+	}
+
+	if err := log.Shutdown(0); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 10 || lines[9] != "N line 9" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	if out.syncs == 0 || !out.closed {
+		t.Fatalf("writer must be synced and closed: %d syncs, closed %v", out.syncs, out.closed)
+	}
+}
+
+func TestAdapterWriterPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+	defer w.Close()
+	adapter := NewAdapterWriter(10, w)
+	log := newLogger()
+	log.AddAdapter("writer", adapter)
+	go adapter.Run()
+	log.Notice("specific language governing permissions")
+	if err := log.Shutdown(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未设置CloseOnDestroy时管道仍可写入
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 256)
+	n, _ := r.Read(b)
+	if !strings.Contains(string(b[:n]), "specific language governing permissions") {
+		t.Fatalf("unexpected output %q", b[:n])
+	}
+}
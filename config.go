@@ -0,0 +1,376 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 配置加载
+package logmo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志配置
+//
+//	{
+//	    "level": "info",
+//	    "adapters": [
+//	        {"name": "console", "type": "console"},
+//	        {
+//	            "name": "file", "type": "file", "level": "warning",
+//	            "formatter": {"type": "json"},
+//	            "options": {"filename": "app.log", "policy": "daily", "compress": "gzip"}
+//	        }
+//	    ]
+//	}
+type Config struct {
+	// 最低输出等级, 为空时输出全部
+	Level string `json:"level"`
+
+	// 适配器
+	Adapters []AdapterConfig `json:"adapters"`
+}
+
+// 适配器配置
+type AdapterConfig struct {
+	// 名称, 为空时使用类型
+	Name string `json:"name"`
+
+	// 类型, 对应 RegisterAdapter 注册的名称
+	Type string `json:"type"`
+
+	// 异步通道长度, 默认 10000
+	ChannelLen int `json:"channel_len"`
+
+	// 是否异步写入, 默认 true
+	Async *bool `json:"async"`
+
+	// 适配器最低输出等级, 为空时不过滤
+	Level string `json:"level"`
+
+	// 异步通道已满时的处理策略: block, block_timeout, drop_newest, drop_oldest, sync
+	Overflow string `json:"overflow"`
+
+	// block_timeout 等待时间
+	OverflowTimeout Duration `json:"overflow_timeout"`
+
+	// 格式化
+	Formatter *FormatterConfig `json:"formatter"`
+
+	// hooks, 按顺序添加
+	Hooks []HookConfig `json:"hooks"`
+
+	// 适配器参数, 由各类型自行解析
+	Options json.RawMessage `json:"options"`
+}
+
+// 格式化配置
+type FormatterConfig struct {
+	// 类型, 对应 RegisterFormatter 注册的名称
+	Type string `json:"type"`
+
+	// 参数
+	Options json.RawMessage `json:"options"`
+}
+
+// hook配置
+type HookConfig struct {
+	// 名称, 为空时使用类型
+	Name string `json:"name"`
+
+	// 类型, 对应 RegisterHook 注册的名称
+	Type string `json:"type"`
+
+	// 参数
+	Options json.RawMessage `json:"options"`
+}
+
+// 时间间隔, 支持 "1s" 形式的字符串或纳秒数
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("logmo: invalid duration %s", b)
+		}
+
+		*d = Duration(n)
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// 适配器创建函数
+type AdapterFactory func(cfg AdapterConfig) (Adapter, error)
+
+// 格式化创建函数
+type FormatterFactory func(options json.RawMessage) (Formatter, error)
+
+// hook创建函数
+type HookFactory func(options json.RawMessage) (Hook, error)
+
+var registry = struct {
+	sync.RWMutex
+	adapters   map[string]AdapterFactory
+	formatters map[string]FormatterFactory
+	hooks      map[string]HookFactory
+}{
+	adapters:   make(map[string]AdapterFactory),
+	formatters: make(map[string]FormatterFactory),
+	hooks:      make(map[string]HookFactory),
+}
+
+// 注册适配器类型, 同名类型将被替换
+func RegisterAdapter(typ string, factory AdapterFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.adapters[typ] = factory
+}
+
+// 注册格式化类型
+func RegisterFormatter(typ string, factory FormatterFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.formatters[typ] = factory
+}
+
+// 注册hook类型
+func RegisterHook(typ string, factory HookFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.hooks[typ] = factory
+}
+
+// 解析参数, 不允许未知字段, 参数为空时不处理
+func DecodeOptions(options json.RawMessage, v interface{}) error {
+	if len(bytes.TrimSpace(options)) == 0 || string(bytes.TrimSpace(options)) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(options))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// 读取JSON配置文件
+func LoadConfigFile(filename string) (*Config, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(Config)
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("logmo: config %s: %v", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// 按配置创建日志, 适配器已启动
+func NewFromConfig(cfg *Config) (*Logger, error) {
+	level, adapters, err := cfg.build()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := newLogger()
+	logger.SetLevel(level)
+	for _, item := range adapters {
+		go item.adapter.Run()
+		logger.AddAdapter(item.name, item.adapter)
+	}
+
+	return logger, nil
+}
+
+// 已创建的适配器
+type namedAdapter struct {
+	name    string
+	adapter Adapter
+}
+
+// 按配置创建等级以及适配器, 适配器未启动
+func (cfg *Config) build() (byte, []namedAdapter, error) {
+	level := byte(DEBUG)
+	if cfg.Level != "" {
+		lv, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		level = lv
+	}
+
+	adapters := []namedAdapter{}
+	names := make(map[string]bool)
+	for _, ac := range cfg.Adapters {
+		name := ac.Name
+		if name == "" {
+			name = ac.Type
+		}
+
+		if names[name] {
+			return 0, nil, fmt.Errorf("logmo: duplicate adapter %q", name)
+		}
+
+		adapter, err := ac.build()
+		if err != nil {
+			return 0, nil, fmt.Errorf("logmo: adapter %q: %v", name, err)
+		}
+
+		names[name] = true
+		adapters = append(adapters, namedAdapter{name: name, adapter: adapter})
+	}
+
+	return level, adapters, nil
+}
+
+// 按配置创建适配器
+func (ac AdapterConfig) build() (Adapter, error) {
+	registry.RLock()
+	factory, ok := registry.adapters[ac.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown adapter type %q", ac.Type)
+	}
+
+	if ac.ChannelLen <= 0 {
+		ac.ChannelLen = 10000
+	}
+
+	adapter, err := factory(ac)
+	if err != nil {
+		return nil, err
+	}
+
+	if ac.Async != nil {
+		adapter.Async(*ac.Async)
+	}
+
+	if ac.Overflow != "" {
+		policy, err := parseOverflow(ac.Overflow)
+		if err != nil {
+			return nil, err
+		}
+
+		o, ok := adapter.(interface {
+			SetOverflow(policy OverflowPolicy, timeout time.Duration)
+		})
+		if !ok {
+			return nil, fmt.Errorf("adapter type %q does not support overflow policy", ac.Type)
+		}
+
+		o.SetOverflow(policy, time.Duration(ac.OverflowTimeout))
+	}
+
+	if ac.Formatter != nil {
+		formatter, err := ac.Formatter.build()
+		if err != nil {
+			return nil, err
+		}
+
+		adapter.SetFormatter(formatter)
+	}
+
+	if ac.Level != "" {
+		level, err := ParseLevel(ac.Level)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := adapter.AddHook("level", &HookLevel{Level: level}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, hc := range ac.Hooks {
+		hook, err := hc.build()
+		if err != nil {
+			return nil, err
+		}
+
+		name := hc.Name
+		if name == "" {
+			name = hc.Type
+		}
+
+		if err := adapter.AddHook(name, hook); err != nil {
+			return nil, err
+		}
+	}
+
+	return adapter, nil
+}
+
+// 按配置创建格式化
+func (fc *FormatterConfig) build() (Formatter, error) {
+	registry.RLock()
+	factory, ok := registry.formatters[fc.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown formatter type %q", fc.Type)
+	}
+
+	return factory(fc.Options)
+}
+
+// 按配置创建hook
+func (hc HookConfig) build() (Hook, error) {
+	registry.RLock()
+	factory, ok := registry.hooks[hc.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown hook type %q", hc.Type)
+	}
+
+	return factory(hc.Options)
+}
+
+// 解析通道策略
+func parseOverflow(s string) (OverflowPolicy, error) {
+	switch strings.ToLower(s) {
+	case "block":
+		return OVERFLOW_BLOCK, nil
+	case "block_timeout":
+		return OVERFLOW_BLOCK_TIMEOUT, nil
+	case "drop_newest":
+		return OVERFLOW_DROP_NEWEST, nil
+	case "drop_oldest":
+		return OVERFLOW_DROP_OLDEST, nil
+	case "sync":
+		return OVERFLOW_SYNC, nil
+	}
+
+	return 0, fmt.Errorf("unknown overflow policy %q", s)
+}
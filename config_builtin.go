@@ -0,0 +1,332 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 内置适配器, 格式化以及hook的配置
+package logmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterAdapter("console", newConsoleFromConfig)
+	RegisterAdapter("file", newFileFromConfig)
+	RegisterAdapter("syslog", newSyslogFromConfig)
+
+	RegisterFormatter("text", func(options json.RawMessage) (Formatter, error) {
+		return new(FormatterText), nil
+	})
+
+	RegisterFormatter("json", func(options json.RawMessage) (Formatter, error) {
+		return new(FormatterJSON), nil
+	})
+
+	RegisterFormatter("pattern", func(options json.RawMessage) (Formatter, error) {
+		var o struct {
+			Layout string `json:"layout"`
+		}
+
+		if err := DecodeOptions(options, &o); err != nil {
+			return nil, err
+		}
+
+		return NewFormatterPattern(o.Layout)
+	})
+
+	RegisterHook("level", func(options json.RawMessage) (Hook, error) {
+		var o struct {
+			Level string `json:"level"`
+		}
+
+		if err := DecodeOptions(options, &o); err != nil {
+			return nil, err
+		}
+
+		level, err := ParseLevel(o.Level)
+		if err != nil {
+			return nil, err
+		}
+
+		return &HookLevel{Level: level}, nil
+	})
+
+	RegisterHook("sampler", func(options json.RawMessage) (Hook, error) {
+		var o struct {
+			Interval   Duration `json:"interval"`
+			First      int      `json:"first"`
+			Thereafter int      `json:"thereafter"`
+		}
+
+		if err := DecodeOptions(options, &o); err != nil {
+			return nil, err
+		}
+
+		return &HookSampler{Interval: time.Duration(o.Interval), First: o.First, Thereafter: o.Thereafter}, nil
+	})
+
+	RegisterHook("rate_limit", func(options json.RawMessage) (Hook, error) {
+		var o struct {
+			Rate  float64 `json:"rate"`
+			Burst int     `json:"burst"`
+			By    string  `json:"by"`
+		}
+
+		if err := DecodeOptions(options, &o); err != nil {
+			return nil, err
+		}
+
+		hook := &HookRateLimit{Rate: o.Rate, Burst: o.Burst}
+		switch strings.ToLower(o.By) {
+		case "", "level":
+			hook.Key = RateLimitByLevel
+		case "template":
+			hook.Key = RateLimitByTemplate
+		case "all":
+			hook.Key = RateLimitAll
+		default:
+			return nil, fmt.Errorf("unknown rate limit key %q", o.By)
+		}
+
+		return hook, nil
+	})
+}
+
+// 控制台适配器
+func newConsoleFromConfig(cfg AdapterConfig) (Adapter, error) {
+	var o struct {
+		Color       string `json:"color"`
+		LevelOnly   bool   `json:"level_only"`
+		Output      string `json:"output"`
+		StderrLevel string `json:"stderr_level"`
+	}
+
+	if err := DecodeOptions(cfg.Options, &o); err != nil {
+		return nil, err
+	}
+
+	var out io.Writer
+	switch strings.ToLower(o.Output) {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		return nil, fmt.Errorf("unknown console output %q", o.Output)
+	}
+
+	adapter := NewAdapterConsoleWriter(cfg.ChannelLen, out)
+	if o.StderrLevel != "" {
+		level, err := ParseLevel(o.StderrLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		adapter.SetErrorWriter(os.Stderr, level)
+	}
+
+	switch strings.ToLower(o.Color) {
+	case "", "auto":
+	case "always":
+		adapter.SetColorMode(COLOR_ALWAYS)
+	case "never":
+		adapter.SetColorMode(COLOR_NEVER)
+	default:
+		return nil, fmt.Errorf("unknown color mode %q", o.Color)
+	}
+
+	if o.LevelOnly {
+		scheme := DefaultColorScheme()
+		scheme.LevelOnly = true
+		adapter.SetColorScheme(scheme)
+	}
+
+	return adapter, nil
+}
+
+// 文件适配器参数
+type fileOptions struct {
+	Filename      string   `json:"filename"`
+	MaxLine       *int     `json:"max_line"`
+	MaxSize       *int     `json:"max_size"`
+	MaxDays       *int64   `json:"max_days"`
+	MaxBackups    int      `json:"max_backups"`
+	MaxTotalSize  int64    `json:"max_total_size"`
+	Rotation      *int     `json:"rotation"`
+	Policy        string   `json:"policy"`
+	NamePattern   string   `json:"name_pattern"`
+	TimeLayout    string   `json:"time_layout"`
+	Compress      string   `json:"compress"`
+	CompressLevel int      `json:"compress_level"`
+	BufferSize    *int     `json:"buffer_size"`
+	FlushInterval Duration `json:"flush_interval"`
+	ReopenCheck   Duration `json:"reopen_check_interval"`
+	Shared        bool     `json:"shared"`
+	LockFile      string   `json:"lock_file"`
+}
+
+// 文件适配器
+func newFileFromConfig(cfg AdapterConfig) (Adapter, error) {
+	var o fileOptions
+	if err := DecodeOptions(cfg.Options, &o); err != nil {
+		return nil, err
+	}
+
+	adapter := NewAdapterFile(cfg.ChannelLen)
+	if o.Filename != "" {
+		adapter.Filename = o.Filename
+	}
+
+	if o.MaxLine != nil {
+		adapter.MaxLine = *o.MaxLine
+	}
+
+	if o.MaxSize != nil {
+		adapter.MaxSize = *o.MaxSize
+	}
+
+	if o.MaxDays != nil {
+		adapter.MaxDays = *o.MaxDays
+	}
+
+	if o.Rotation != nil {
+		adapter.Rotation = *o.Rotation
+	}
+
+	if o.BufferSize != nil {
+		adapter.BufferSize = *o.BufferSize
+	}
+
+	if o.FlushInterval > 0 {
+		adapter.SetFlushInterval(time.Duration(o.FlushInterval))
+	}
+
+	adapter.ReopenCheckInterval = time.Duration(o.ReopenCheck)
+	adapter.Shared = o.Shared
+	adapter.LockFile = o.LockFile
+	adapter.MaxBackups = o.MaxBackups
+	adapter.MaxTotalSize = o.MaxTotalSize
+	adapter.NamePattern = o.NamePattern
+	adapter.TimeLayout = o.TimeLayout
+
+	switch strings.ToLower(o.Policy) {
+	case "", "default":
+	case "hourly":
+		adapter.Policy = &RotationHourly{}
+	case "daily":
+		adapter.Policy = &RotationDaily{}
+	case "weekly":
+		adapter.Policy = &RotationWeekly{}
+	case "size":
+		adapter.Policy = &RotationSize{MaxSize: adapter.MaxSize}
+	case "line":
+		adapter.Policy = &RotationLine{MaxLine: adapter.MaxLine}
+	case "never":
+		adapter.Policy = &RotationNever{}
+	default:
+		return nil, fmt.Errorf("unknown rotation policy %q", o.Policy)
+	}
+
+	switch strings.ToLower(o.Compress) {
+	case "", "none":
+	case "gzip":
+		adapter.Compressor = &CompressorGzip{Level: o.CompressLevel}
+	default:
+		return nil, fmt.Errorf("unknown compressor %q", o.Compress)
+	}
+
+	return adapter, nil
+}
+
+// syslog设施名称
+var syslogFacilities = map[string]SyslogFacility{
+	"kern":     FACILITY_KERN,
+	"user":     FACILITY_USER,
+	"mail":     FACILITY_MAIL,
+	"daemon":   FACILITY_DAEMON,
+	"auth":     FACILITY_AUTH,
+	"syslog":   FACILITY_SYSLOG,
+	"lpr":      FACILITY_LPR,
+	"news":     FACILITY_NEWS,
+	"uucp":     FACILITY_UUCP,
+	"cron":     FACILITY_CRON,
+	"authpriv": FACILITY_AUTHPRIV,
+	"ftp":      FACILITY_FTP,
+	"local0":   FACILITY_LOCAL0,
+	"local1":   FACILITY_LOCAL1,
+	"local2":   FACILITY_LOCAL2,
+	"local3":   FACILITY_LOCAL3,
+	"local4":   FACILITY_LOCAL4,
+	"local5":   FACILITY_LOCAL5,
+	"local6":   FACILITY_LOCAL6,
+	"local7":   FACILITY_LOCAL7,
+}
+
+// syslog适配器
+func newSyslogFromConfig(cfg AdapterConfig) (Adapter, error) {
+	var o struct {
+		Network          string   `json:"network"`
+		Addr             string   `json:"addr"`
+		Format           string   `json:"format"`
+		Facility         string   `json:"facility"`
+		Hostname         string   `json:"hostname"`
+		AppName          string   `json:"app_name"`
+		StructuredDataID *string  `json:"sd_id"`
+		Timeout          Duration `json:"timeout"`
+	}
+
+	if err := DecodeOptions(cfg.Options, &o); err != nil {
+		return nil, err
+	}
+
+	adapter := NewAdapterSyslog(cfg.ChannelLen, o.Network, o.Addr)
+	switch strings.ToLower(o.Format) {
+	case "", "rfc5424":
+	case "rfc3164":
+		adapter.Format = SYSLOG_RFC3164
+	default:
+		return nil, fmt.Errorf("unknown syslog format %q", o.Format)
+	}
+
+	if o.Facility != "" {
+		facility, ok := syslogFacilities[strings.ToLower(o.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", o.Facility)
+		}
+
+		adapter.Facility = facility
+	}
+
+	if o.Hostname != "" {
+		adapter.Hostname = o.Hostname
+	}
+
+	if o.AppName != "" {
+		adapter.AppName = o.AppName
+	}
+
+	if o.StructuredDataID != nil {
+		adapter.StructuredDataID = *o.StructuredDataID
+	}
+
+	if o.Timeout > 0 {
+		adapter.Timeout = time.Duration(o.Timeout)
+	}
+
+	return adapter, nil
+}
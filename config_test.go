@@ -0,0 +1,234 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 配置测试
+package logmo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	RegisterAdapter("memory", func(cfg AdapterConfig) (Adapter, error) {
+		return new(memoryAdapter), nil
+	})
+
+	data := `{
+		"level": "notice",
+		"adapters": [
+			{"type": "memory"},
+			{
+				"name": "file", "type": "file", "level": "warn", "overflow": "drop_newest",
+				"formatter": {"type": "pattern", "options": {"layout": "%level %msg"}},
+				"options": {"filename": ` + jsonString(filename) + `, "policy": "daily", "compress": "gzip", "flush_interval": "10ms"}
+			}
+		]
+	}`
+
+	path := filepath.Join(dir, "logmo.json")
+	os.WriteFile(path, []byte(data), 0660)
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.GetLevel() != NOTICE {
+		t.Fatalf("unexpected level: %d", log.GetLevel())
+	}
+
+	log.Info("dropped by logger")
+	log.Notice("dropped by file")
+	log.Err("specific language governing permissions")
+	if err := log.Shutdown(0); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(filename)
+	if !strings.HasSuffix(string(b), "ERROR specific language governing permissions\n") || strings.Contains(string(b), "dropped") {
+		t.Fatalf("unexpected file content: %q", b)
+	}
+
+	for _, bad := range []string{
+		`{"adapters": [{"type": "unknown"}]}`,
+		`{"adapters": [{"type": "file", "options": {"filenme": "x"}}]}`,
+		`{"adapters": [{"type": "memory"}, {"type": "memory"}]}`,
+		`{"level": "loud"}`,
+	} {
+		cfg := new(Config)
+		if err := json.Unmarshal([]byte(bad), cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Fatalf("config %s must fail", bad)
+		}
+	}
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestLoggerReload(t *testing.T) {
+	var created []*memoryAdapter
+	RegisterAdapter("memory", func(cfg AdapterConfig) (Adapter, error) {
+		mem := new(memoryAdapter)
+		created = append(created, mem)
+		return mem, nil
+	})
+
+	log, err := NewFromConfig(&Config{Adapters: []AdapterConfig{{Type: "memory"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Info("first")
+	if err := log.Reload(&Config{Adapters: []AdapterConfig{{Type: "unknown"}}}); err == nil {
+		t.Fatal("invalid config must fail")
+	}
+
+	if err := log.Reload(&Config{Level: "error", Adapters: []AdapterConfig{{Name: "a", Type: "memory"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	log.Info("filtered")
+	log.Err("second")
+	if len(created) != 2 || len(created[0].messages) != 1 || created[1].last().GetMessage() != "second" {
+		t.Fatalf("unexpected messages after reload")
+	}
+
+	if _, err := log.GetAdapter("memory"); err == nil {
+		t.Fatal("old adapter must be removed")
+	}
+
+	if err := log.AddAdapter("a", new(memoryAdapter)); err == nil {
+		t.Fatal("duplicate adapter must fail")
+	}
+
+	replacement := new(memoryAdapter)
+	log.ReplaceAdapter("a", replacement)
+	log.Err("third")
+	if replacement.last().GetMessage() != "third" {
+		t.Fatal("message must go to the replacement adapter")
+	}
+
+	// 关闭后重新加载或替换的适配器被销毁
+	var sinks []*memorySink
+	RegisterAdapter("memory-async", func(cfg AdapterConfig) (Adapter, error) {
+		sink := new(memorySink)
+		sinks = append(sinks, sink)
+		return NewBaseAdapter(sink, 10), nil
+	})
+
+	log.Close()
+	if err := log.Reload(&Config{Adapters: []AdapterConfig{{Type: "memory-async"}}}); err != ErrClosed {
+		t.Fatalf("reload after close must fail: %v", err)
+	}
+
+	sink := new(memorySink)
+	if err := log.ReplaceAdapter("a", NewBaseAdapter(sink, 10)); err != ErrClosed {
+		t.Fatalf("replace after close must fail: %v", err)
+	}
+
+	if len(sinks) != 1 || !sinks[0].closed || !sink.closed {
+		t.Fatal("adapters installed after close must be destroyed")
+	}
+
+	// 间隔小于等于0时使用默认间隔
+	stop := log.WatchConfigFile(filepath.Join(t.TempDir(), "logmo.json"), 0)
+	time.Sleep(10 * time.Millisecond)
+	stop()
+}
+
+func TestLoggerReloadConcurrent(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		sinks []*memorySink
+	)
+
+	RegisterAdapter("memory-async", func(cfg AdapterConfig) (Adapter, error) {
+		sink := new(memorySink)
+		lock.Lock()
+		sinks = append(sinks, sink)
+		lock.Unlock()
+		return NewBaseAdapter(sink, 1024), nil
+	})
+
+	cfg := &Config{Adapters: []AdapterConfig{{Name: "memory", Type: "memory-async"}}}
+	log, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written int64
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if err := log.Info("specific language governing permissions"); err != nil {
+					t.Error(err)
+					return
+				}
+
+				atomic.AddInt64(&written, 1)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		time.Sleep(time.Millisecond)
+		if err := log.Reload(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+	if err := log.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, sink := range sinks {
+		total += sink.count()
+	}
+
+	if int64(total) != written {
+		t.Fatalf("accepted %d messages, wrote %d", written, total)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 适配器写入错误
+package logmo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// 适配器或日志已关闭
+var ErrClosed = errors.New("logmo: closed")
+
+// 错误处理回调
+type ErrorHandler func(err error)
+
+// 可报告异步写入错误的适配器
+// Logger.AddAdapter 会自动设置回调, 异步写入失败时通过Logger的错误处理上报
+type ErrorReporter interface {
+	SetErrorHandler(handler ErrorHandler)
+}
+
+// 单个适配器写入错误
+type AdapterError struct {
+	// 适配器名称
+	Name string
+
+	Err error
+}
+
+func (e *AdapterError) Error() string {
+	return fmt.Sprintf("adapter(%s): %v", e.Name, e.Err)
+}
+
+func (e *AdapterError) Unwrap() error {
+	return e.Err
+}
+
+// 多个适配器写入错误
+type AdapterErrors []*AdapterError
+
+func (errs AdapterErrors) Error() string {
+	parts := make([]string, len(errs))
+	for i, e := range errs {
+		parts[i] = e.Error()
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func (errs AdapterErrors) Unwrap() []error {
+	list := make([]error, len(errs))
+	for i, e := range errs {
+		list[i] = e
+	}
+
+	return list
+}
+
+// 关闭日志错误
+type ShutdownError struct {
+	// 未能写入的信息数量
+	Lost int
+
+	// 各适配器关闭错误
+	Errors AdapterErrors
+}
+
+func (e *ShutdownError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("logmo: shutdown lost %d messages", e.Lost)
+	}
+
+	return fmt.Sprintf("logmo: shutdown lost %d messages: %v", e.Lost, e.Errors)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+
+	return e.Errors
+}
+
+// 适配器写入统计
+type AdapterStats struct {
+	// 成功写入(异步为成功进入队列)次数
+	Writes uint64
+
+	// 写入失败次数
+	Errors uint64
+
+	// 通道已满被丢弃的数量
+	Dropped uint64
+
+	// 被hook或中间件过滤的数量
+	Filtered uint64
+}
+
+// 适配器写入计数器
+type adapterCounter struct {
+	writes uint64
+	errors uint64
+}
+
+func (counter *adapterCounter) stats() AdapterStats {
+	return AdapterStats{
+		Writes: atomic.LoadUint64(&counter.writes),
+		Errors: atomic.LoadUint64(&counter.errors),
+	}
+}
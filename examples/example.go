@@ -45,5 +45,6 @@ func main() {
 	logmo.Info("specific language governing permissions")
 	logmo.Debug("specific language governing permissions")
 
-	time.Sleep(time.Second * 1)
+	// 写入全部等待中的日志后关闭
+	logmo.Shutdown(time.Second)
 }
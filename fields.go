@@ -0,0 +1,109 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 结构化附加字段
+package logmo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 附加字段
+type Fields map[string]interface{}
+
+// 合并字段, 返回新的字段集合, 同名字段以other为准
+func (fields Fields) merge(other Fields) Fields {
+	if len(fields) == 0 && len(other) == 0 {
+		return nil
+	}
+
+	merged := make(Fields, len(fields)+len(other))
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	for k, v := range other {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// 按key排序返回字段名
+func (fields Fields) Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// 以key=value方式输出, 按key排序
+func (fields Fields) String() string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, k := range fields.Keys() {
+		parts = append(parts, k+"="+formatFieldValue(fields[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// 格式化字段值, 包含空白或特殊字符时加引号
+func formatFieldValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case string:
+		s = value
+	case error:
+		s = value.Error()
+	case fmt.Stringer:
+		s = value.String()
+	default:
+		s = fmt.Sprint(value)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return s
+}
+
+// 将 key, value 列表转换为字段
+// 非字符串key使用fmt.Sprint转换, 缺少value的key值为nil
+func makeFields(kv ...interface{}) Fields {
+	fields := make(Fields, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		if i+1 < len(kv) {
+			fields[key] = kv[i+1]
+		} else {
+			fields[key] = nil
+		}
+	}
+
+	return fields
+}
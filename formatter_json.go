@@ -0,0 +1,90 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// JSON格式化, 每条信息输出一行JSON
+package logmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type FormatterJSON struct{}
+
+// JSON输出结构
+type jsonMessage struct {
+	Time    string      `json:"time"`
+	Level   string      `json:"level"`
+	Prefix  string      `json:"prefix,omitempty"`
+	File    string      `json:"file,omitempty"`
+	Line    int         `json:"line,omitempty"`
+	Pid     int         `json:"pid"`
+	Id      int64       `json:"id"`
+	Message string      `json:"msg"`
+	Data    interface{} `json:"data,omitempty"`
+	Fields  Fields      `json:"fields,omitempty"`
+}
+
+func (format *FormatterJSON) Format(message Message) ([]byte, error) {
+	m := jsonMessage{
+		Time:    message.GetTime().Format(time.RFC3339Nano),
+		Level:   LevelName(message.GetLevel()),
+		Prefix:  message.GetPrefix(),
+		File:    message.GetFile(),
+		Line:    message.GetLine(),
+		Pid:     message.GetPID(),
+		Id:      message.GetID(),
+		Message: message.GetMessage(),
+		Data:    jsonValue(message.GetData()),
+	}
+
+	if fields := message.GetFields(); len(fields) > 0 {
+		m.Fields = make(Fields, len(fields))
+		for k, v := range fields {
+			m.Fields[k] = jsonValue(v)
+		}
+	}
+
+	b, err := json.Marshal(&m)
+	if err != nil {
+		// 附加数据无法序列化时, 仍然输出信息本身
+		if data := message.GetData(); data != nil {
+			m.Data = fmt.Sprintf("%+v", data)
+		}
+
+		m.Fields = nil
+		if fields := message.GetFields(); len(fields) > 0 {
+			m.Fields = make(Fields, len(fields))
+			for k, v := range fields {
+				m.Fields[k] = fmt.Sprintf("%+v", v)
+			}
+		}
+
+		return json.Marshal(&m)
+	}
+
+	return b, nil
+}
+
+// error 类型序列化后为空对象, 转换为错误信息
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		if _, ok := v.(json.Marshaler); !ok {
+			return err.Error()
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,285 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 模板格式化
+package logmo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 默认时间格式
+const defaultTimeLayout = "2006/01/02 15:04:05"
+
+// 时间格式常量名
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// 模板片段
+type patternSegment struct {
+	// 占位符名称, 为空时输出literal
+	name    string
+	arg     string
+	literal string
+
+	width     int
+	precision int
+	left      bool
+}
+
+// 模板格式化
+// 占位符格式为 %[-][width][.precision]name[{arg}], 支持:
+//
+//	%time{layout}  时间, layout 可为 RFC3339 等常量名或Go时间格式, 默认 2006/01/02 15:04:05
+//	%level         等级名称
+//	%prefix        前缀
+//	%file          文件
+//	%line          行号
+//	%pid           进程号
+//	%id            信息编号
+//	%msg           信息
+//	%field{name}   指定附加字段
+//	%fields        全部附加字段, key=value 形式
+//	%data          附加数据
+//	%%             输出 %
+//
+// width 为最小宽度, 默认右对齐, 加 - 左对齐; precision 为最大宽度, 超出截断
+// 如: "%time{RFC3339} %-9level [%file:%line] %msg %fields"
+type FormatterPattern struct {
+	// 模板
+	Layout string
+
+	segments []patternSegment
+	err      error
+	once     sync.Once
+}
+
+// 创建模板格式化
+func NewFormatterPattern(layout string) (*FormatterPattern, error) {
+	segments, err := parsePattern(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	format := &FormatterPattern{Layout: layout, segments: segments}
+	format.once.Do(func() {})
+	return format, nil
+}
+
+func (format *FormatterPattern) Format(message Message) ([]byte, error) {
+	// 直接构造时延迟解析
+	format.once.Do(func() {
+		format.segments, format.err = parsePattern(format.Layout)
+	})
+
+	if format.err != nil {
+		return nil, format.err
+	}
+
+	var buf bytes.Buffer
+	for _, seg := range format.segments {
+		if seg.name == "" {
+			buf.WriteString(seg.literal)
+			continue
+		}
+
+		writePadded(&buf, seg, patternValue(seg, message))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 获取占位符的值
+func patternValue(seg patternSegment, message Message) string {
+	switch seg.name {
+	case "time":
+		return message.GetTime().Format(seg.arg)
+	case "level":
+		return LevelName(message.GetLevel())
+	case "prefix":
+		return message.GetPrefix()
+	case "file":
+		return message.GetFile()
+	case "line":
+		return strconv.Itoa(message.GetLine())
+	case "pid":
+		return strconv.Itoa(message.GetPID())
+	case "id":
+		return strconv.FormatInt(message.GetID(), 10)
+	case "msg":
+		return message.GetMessage()
+	case "field":
+		if v, ok := message.GetFields()[seg.arg]; ok {
+			return formatFieldValue(v)
+		}
+		return ""
+	case "fields":
+		return message.GetFields().String()
+	case "data":
+		if data := message.GetData(); data != nil {
+			return fmt.Sprintf("%+v", data)
+		}
+		return ""
+	}
+
+	return ""
+}
+
+// 按宽度输出
+func writePadded(buf *bytes.Buffer, seg patternSegment, s string) {
+	if seg.precision > 0 && utf8.RuneCountInString(s) > seg.precision {
+		s = string([]rune(s)[:seg.precision])
+	}
+
+	pad := seg.width - utf8.RuneCountInString(s)
+	if pad <= 0 {
+		buf.WriteString(s)
+		return
+	}
+
+	if seg.left {
+		buf.WriteString(s)
+		buf.Write(bytes.Repeat([]byte{' '}, pad))
+		return
+	}
+
+	buf.Write(bytes.Repeat([]byte{' '}, pad))
+	buf.WriteString(s)
+}
+
+// 解析模板
+func parsePattern(layout string) ([]patternSegment, error) {
+	segments := []patternSegment{}
+	literal := []byte{}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			literal = append(literal, layout[i])
+			continue
+		}
+
+		if i+1 < len(layout) && layout[i+1] == '%' {
+			literal = append(literal, '%')
+			i++
+			continue
+		}
+
+		seg, n, err := parsePlaceholder(layout[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("logmo: pattern %q at %d: %v", layout, i, err)
+		}
+
+		if len(literal) > 0 {
+			segments = append(segments, patternSegment{literal: string(literal)})
+			literal = literal[:0]
+		}
+
+		segments = append(segments, seg)
+		i += n
+	}
+
+	if len(literal) > 0 {
+		segments = append(segments, patternSegment{literal: string(literal)})
+	}
+
+	return segments, nil
+}
+
+// 解析单个占位符, 返回占位符及消耗的字节数
+func parsePlaceholder(s string) (patternSegment, int, error) {
+	seg := patternSegment{}
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		seg.left = true
+		i++
+	}
+
+	start := i
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > start {
+		seg.width, _ = strconv.Atoi(s[start:i])
+	}
+
+	if i < len(s) && s[i] == '.' {
+		i++
+		start = i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return seg, 0, errors.New("missing precision")
+		}
+		seg.precision, _ = strconv.Atoi(s[start:i])
+	}
+
+	start = i
+	for i < len(s) && (s[i] >= 'a' && s[i] <= 'z') {
+		i++
+	}
+	seg.name = s[start:i]
+
+	if i < len(s) && s[i] == '{' {
+		end := bytes.IndexByte([]byte(s[i:]), '}')
+		if end < 0 {
+			return seg, 0, errors.New("unterminated {")
+		}
+		seg.arg = s[i+1 : i+end]
+		i += end + 1
+	}
+
+	switch seg.name {
+	case "time":
+		if seg.arg == "" {
+			seg.arg = defaultTimeLayout
+		} else if layout, ok := timeLayouts[seg.arg]; ok {
+			seg.arg = layout
+		}
+	case "field":
+		if seg.arg == "" {
+			return seg, 0, errors.New("%field requires a name")
+		}
+	case "level", "prefix", "file", "line", "pid", "id", "msg", "fields", "data":
+	case "":
+		return seg, 0, errors.New("missing placeholder name")
+	default:
+		return seg, 0, fmt.Errorf("unknown placeholder %%%s", seg.name)
+	}
+
+	return seg, i, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 格式化测试
+package logmo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestMessage() *DefaultMessage {
+	return &DefaultMessage{
+		Level:   WARNING,
+		File:    "t.go",
+		Line:    5,
+		Message: "specific language governing permissions",
+		Time:    time.Date(2015, 6, 1, 8, 30, 0, 123, time.UTC),
+		Prefix:  "W",
+		Pid:     100,
+		Id:      7,
+		Fields:  Fields{"request_id": "r-1", "err": errors.New("disk full")},
+	}
+}
+
+func TestFormatterJSON(t *testing.T) {
+	b, err := new(FormatterJSON).Format(newTestMessage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("invalid json %s: %v", b, err)
+	}
+
+	if m["time"] != "2015-06-01T08:30:00.000000123Z" || m["level"] != "WARNING" || m["line"] != float64(5) {
+		t.Fatalf("unexpected json: %s", b)
+	}
+
+	fields, _ := m["fields"].(map[string]interface{})
+	if fields["request_id"] != "r-1" || fields["err"] != "disk full" {
+		t.Fatalf("unexpected fields: %s", b)
+	}
+
+	if _, ok := m["data"]; ok {
+		t.Fatalf("empty data must be omitted: %s", b)
+	}
+}
+
+func TestFormatterPattern(t *testing.T) {
+	format, err := NewFormatterPattern("%time{RFC3339} %-9level|%5.3prefix|[%file:%line] %msg %field{request_id} 100%%")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestMessage()
+	m.Prefix = "WARN"
+	b, err := format.Format(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2015-06-01T08:30:00Z WARNING  |  WAR|[t.go:5] specific language governing permissions r-1 100%"
+	if string(b) != expected {
+		t.Fatalf("unexpected output:\n%s\n%s", b, expected)
+	}
+
+	for _, layout := range []string{"%unknown", "%field", "%time{RFC3339", "%-"} {
+		if _, err := NewFormatterPattern(layout); err == nil {
+			t.Fatalf("layout %q must fail", layout)
+		}
+	}
+}
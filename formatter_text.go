@@ -33,6 +33,10 @@ func (format *FormatterText) Format( message Message ) ( []byte, error ) {
     } else {
         msg = fmt.Sprintf("%s [%s] %s", createat, prefix, msg)
     }
+    
+    if fields := message.GetFields(); len(fields) > 0 {
+        msg += " " + fields.String()
+    }
    
 	return []byte(msg), nil
 }
\ No newline at end of file
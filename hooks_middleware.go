@@ -0,0 +1,148 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 可修改信息的hook中间件
+package logmo
+
+import (
+	"fmt"
+)
+
+// 可修改的日志信息
+type MutableMessage interface {
+	Message
+
+	// 设置日志信息, 如脱敏
+	SetMessage(msg string)
+
+	// 改写日志等级
+	SetLevel(level byte)
+
+	// 改写前缀
+	SetPrefix(prefix string)
+
+	// 设置附加数据
+	SetData(data interface{})
+
+	// 设置附加字段
+	SetField(key string, value interface{})
+
+	// 删除附加字段
+	DeleteField(key string)
+}
+
+// 中间件处理结果
+type HookResult byte
+
+const (
+	// 保留信息, 继续执行后续中间件
+	HOOK_KEEP HookResult = iota
+
+	// 丢弃信息
+	HOOK_DROP
+)
+
+// 中间件, 可修改信息并决定保留或丢弃
+type Middleware interface {
+	Handle(message MutableMessage) HookResult
+}
+
+// 函数中间件
+type MiddlewareFunc func(message MutableMessage) HookResult
+
+func (f MiddlewareFunc) Handle(message MutableMessage) HookResult {
+	return f(message)
+}
+
+// 按添加顺序执行的hook以及中间件
+type middlewareChain []namedMiddleware
+
+type namedMiddleware struct {
+	name string
+
+	// 只读hook, Fire返回错误时丢弃信息
+	hook Hook
+
+	middleware Middleware
+}
+
+// 是否已存在
+func (chain middlewareChain) has(name string) bool {
+	for _, m := range chain {
+		if m.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 追加到末尾, 返回新的中间件链, 同名已存在时返回错误
+// 中间件链只整体替换不原地修改, 执行时无需持有锁
+func (chain middlewareChain) add(m namedMiddleware) (middlewareChain, error) {
+	if chain.has(m.name) {
+		return chain, fmt.Errorf("logmo: hook %q already exists", m.name)
+	}
+
+	added := make(middlewareChain, len(chain), len(chain)+1)
+	copy(added, chain)
+	return append(added, m), nil
+}
+
+// 删除, 返回新的中间件链
+func (chain middlewareChain) remove(name string) middlewareChain {
+	removed := make(middlewareChain, 0, len(chain))
+	for _, m := range chain {
+		if m.name != name {
+			removed = append(removed, m)
+		}
+	}
+
+	return removed
+}
+
+// 在信息上直接执行, 返回是否保留
+func (chain middlewareChain) handle(message MutableMessage) bool {
+	for _, m := range chain {
+		if m.hook != nil {
+			if err := m.hook.Fire(message); err != nil {
+				return false
+			}
+			continue
+		}
+
+		if m.middleware.Handle(message) == HOOK_DROP {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 执行并返回处理后的信息, 信息可能被多个适配器共享
+// 遇到第一个中间件时复制信息, 只有只读hook时不复制
+func (chain middlewareChain) run(message Message) (Message, bool) {
+	for i, m := range chain {
+		if m.hook == nil {
+			clone := cloneMessage(message)
+			return clone, chain[i:].handle(clone)
+		}
+
+		if err := m.hook.Fire(message); err != nil {
+			return message, false
+		}
+	}
+
+	return message, true
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 日志限流
+package logmo
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 信息被限流丢弃
+var ErrRateLimited = errors.New("logmo: message rate limited")
+
+// 限流hook, 按令牌桶算法限制每个分组每秒输出的信息数
+type HookRateLimit struct {
+	// 每秒允许的信息数
+	Rate float64
+
+	// 令牌桶容量, 即允许的突发信息数, 小于等于0时取Rate
+	Burst int
+
+	// 限流分组, 为空时按等级分组
+	Key func(message Message) string
+
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+	dropped uint64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// 按等级分组
+func RateLimitByLevel(message Message) string {
+	return LevelName(message.GetLevel())
+}
+
+// 按等级以及信息模板分组, 没有模板的信息按等级分组
+func RateLimitByTemplate(message Message) string {
+	return LevelName(message.GetLevel()) + " " + messageTemplate(message)
+}
+
+// 全部信息共用一个分组
+func RateLimitAll(message Message) string {
+	return ""
+}
+
+func (hr *HookRateLimit) Fire(message Message) error {
+	key := hr.Key
+	if key == nil {
+		key = RateLimitByLevel
+	}
+
+	burst := float64(hr.Burst)
+	if burst <= 0 {
+		burst = hr.Rate
+	}
+
+	name := key(message)
+	now := time.Now()
+	hr.lock.Lock()
+	if hr.buckets == nil {
+		hr.buckets = make(map[string]*tokenBucket)
+		hr.swept = now
+	}
+
+	// 每秒清理一次已补满的令牌桶, 补满的桶与新建的桶相同
+	if now.Sub(hr.swept) >= time.Second {
+		for k, b := range hr.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*hr.Rate >= burst {
+				delete(hr.buckets, k)
+			}
+		}
+
+		hr.swept = now
+	}
+
+	bucket, ok := hr.buckets[name]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		hr.buckets[name] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * hr.Rate
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+
+	bucket.last = now
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	hr.lock.Unlock()
+
+	if allowed {
+		return nil
+	}
+
+	atomic.AddUint64(&hr.dropped, 1)
+	return ErrRateLimited
+}
+
+// 获取被限流丢弃的信息数
+func (hr *HookRateLimit) Dropped() uint64 {
+	return atomic.LoadUint64(&hr.dropped)
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 日志采样
+package logmo
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 信息被采样丢弃
+var ErrSampled = errors.New("logmo: message sampled")
+
+// 采样hook, 每个周期内同一等级同一模板的信息先输出前First条,
+// 之后每Thereafter条输出一条
+type HookSampler struct {
+	// 采样周期, 小于等于0时为1秒
+	Interval time.Duration
+
+	// 每个周期先输出的条数
+	First int
+
+	// 超出First后每Thereafter条输出一条, 小于等于0时丢弃超出的信息
+	Thereafter int
+
+	lock    sync.Mutex
+	start   time.Time
+	counts  map[samplerKey]int
+	dropped uint64
+}
+
+type samplerKey struct {
+	level    byte
+	template string
+}
+
+func (hs *HookSampler) Fire(message Message) error {
+	interval := hs.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	key := samplerKey{level: message.GetLevel(), template: messageTemplate(message)}
+	now := time.Now()
+	hs.lock.Lock()
+	// 进入新的周期时重新计数
+	if hs.counts == nil || now.Sub(hs.start) >= interval {
+		hs.counts = make(map[samplerKey]int)
+		hs.start = now
+	}
+
+	hs.counts[key]++
+	n := hs.counts[key]
+	hs.lock.Unlock()
+
+	if n <= hs.First || (hs.Thereafter > 0 && (n-hs.First)%hs.Thereafter == 0) {
+		return nil
+	}
+
+	atomic.AddUint64(&hs.dropped, 1)
+	return ErrSampled
+}
+
+// 获取被采样丢弃的信息数
+func (hs *HookSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&hs.dropped)
+}
@@ -0,0 +1,106 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// hook测试
+package logmo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHookSampler(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 0)
+	sampler := &HookSampler{Interval: time.Hour, First: 3, Thereafter: 10}
+	adapter.AddHook("sampler", sampler)
+
+	log := newLogger()
+	log.AddAdapter("memory", adapter)
+	for i := 0; i < 100; i++ {
+		log.SyncWarn("retry %d", i)
+		log.SyncInfo("retry %d", i)
+	}
+
+	log.SyncWarn("other")
+	if sink.count() != 25 || sampler.Dropped() != 176 {
+		t.Fatalf("unexpected sampling: %d lines, %d dropped", sink.count(), sampler.Dropped())
+	}
+
+	sampler.Interval = time.Nanosecond
+	log.SyncWarn("retry %d", 100)
+	if sink.count() != 26 {
+		t.Fatalf("sampler must reset on new interval, got %d lines", sink.count())
+	}
+}
+
+func TestHookRateLimit(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 0)
+	limit := &HookRateLimit{Rate: 0.001, Burst: 5}
+	adapter.AddHook("limit", limit)
+
+	log := newLogger()
+	log.AddAdapter("memory", adapter)
+	for i := 0; i < 20; i++ {
+		log.SyncWarn("flood %d", i)
+		log.SyncErr("flood %d", i)
+	}
+
+	if sink.count() != 10 || limit.Dropped() != 30 {
+		t.Fatalf("unexpected rate limit: %d lines, %d dropped", sink.count(), limit.Dropped())
+	}
+
+	limit = &HookRateLimit{Rate: 1000, Burst: 1, Key: RateLimitAll}
+	adapter.DeleteHook("limit")
+	adapter.AddHook("limit", limit)
+	log.SyncWarn("first")
+	log.SyncErr("second")
+	time.Sleep(5 * time.Millisecond)
+	log.SyncErr("third")
+	if sink.count() != 12 || limit.Dropped() != 1 {
+		t.Fatalf("unexpected rate limit: %d lines, %d dropped", sink.count(), limit.Dropped())
+	}
+}
+
+func TestHookRateLimitBuckets(t *testing.T) {
+	sink := new(memorySink)
+	adapter := NewBaseAdapter(sink, 0)
+	limit := &HookRateLimit{Rate: 0.001, Burst: 1, Key: RateLimitByTemplate}
+	adapter.AddHook("limit", limit)
+
+	log := newLogger()
+	log.AddAdapter("memory", adapter)
+	for i := 0; i < 100; i++ {
+		log.Write(WARNING, "W", fmt.Sprintf("flood %d", i), nil, true)
+	}
+
+	// Write写入的信息没有模板, 共用等级分组
+	if sink.count() != 1 || limit.Dropped() != 99 {
+		t.Fatalf("unexpected rate limit: %d lines, %d dropped", sink.count(), limit.Dropped())
+	}
+
+	// 清理令牌桶时只删除已补满的桶, 未补满的分组仍被限流
+	limit = &HookRateLimit{Rate: 0.5, Burst: 1, Key: RateLimitByTemplate}
+	adapter.DeleteHook("limit")
+	adapter.AddHook("limit", limit)
+	log.SyncWarn("hot")
+	time.Sleep(1100 * time.Millisecond)
+	log.SyncWarn("cold")
+	log.SyncWarn("hot")
+	if sink.count() != 3 || limit.Dropped() != 1 {
+		t.Fatalf("unexpected rate limit: %d lines, %d dropped", sink.count(), limit.Dropped())
+	}
+}
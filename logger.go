@@ -16,12 +16,15 @@
 package logmo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,6 +40,72 @@ const (
 	DEBUG
 )
 
+// 日志等级名称
+var levelNames = []string{
+	EMERGENCY: "EMERGENCY",
+	ALERT:     "ALERT",
+	CRITICAL:  "CRITICAL",
+	ERROR:     "ERROR",
+	WARNING:   "WARNING",
+	NOTICE:    "NOTICE",
+	INFO:      "INFO",
+	DEBUG:     "DEBUG",
+}
+
+// 获取日志等级名称
+func LevelName(level byte) string {
+	if int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+
+	return fmt.Sprintf("LEVEL(%d)", level)
+}
+
+// 日志等级前缀
+var levelPrefixes = []string{
+	EMERGENCY: "M",
+	ALERT:     "A",
+	CRITICAL:  "C",
+	ERROR:     "E",
+	WARNING:   "W",
+	NOTICE:    "N",
+	INFO:      "I",
+	DEBUG:     "D",
+}
+
+// 获取日志等级前缀, 与 Emerg...Debug 使用的前缀一致
+func levelPrefix(level byte) string {
+	if int(level) < len(levelPrefixes) {
+		return levelPrefixes[level]
+	}
+
+	return LevelName(level)
+}
+
+// 日志等级简称
+var levelAliases = map[string]byte{
+	"EMERG": EMERGENCY,
+	"CRIT":  CRITICAL,
+	"ERR":   ERROR,
+	"WARN":  WARNING,
+}
+
+// 按名称解析日志等级, 不区分大小写
+func ParseLevel(name string) (byte, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for level, n := range levelNames {
+		if n == name {
+			return byte(level), nil
+		}
+	}
+
+	if level, ok := levelAliases[name]; ok {
+		return level, nil
+	}
+
+	return 0, fmt.Errorf("logmo: unknown level %q", name)
+}
+
 type Logger struct {
 	// 适配器
 	adapters map[string]Adapter
@@ -44,188 +113,667 @@ type Logger struct {
 	// 定义错误深度
 	ExtraCalldepth int
 
-	// 信息记数器
+	// 信息记数器, 原子操作读写
 	counter int64
 
+	// 最低输出等级加1, 原子操作读写
+	// 0 表示未设置, 输出全部等级, 使零值Logger与旧版本行为一致
+	level int32
+
+	// 适配器写入统计
+	counters map[string]*adapterCounter
+
+	// 错误处理
+	errorHandler ErrorHandler
+
+	// context字段提取器
+	extractors []contextExtractor
+
+	// 中间件, 分发到适配器之前按添加顺序执行
+	middlewares middlewareChain
+
+	// 被中间件过滤的数量
+	filtered uint64
+
+	// 是否已关闭
+	closed int32
+
 	lock sync.Mutex
+
+	// 当前适配器集合进行中的分发, 关闭或替换适配器时一并替换并等待旧集合的分发完成
+	dispatching *sync.WaitGroup
+
+	// 附加字段
+	fields Fields
+
+	// 父级日志, 子日志共享父级的适配器
+	parent *Logger
+}
+
+// 获取根日志
+func (log *Logger) root() *Logger {
+	if log.parent != nil {
+		return log.parent
+	}
+
+	return log
+}
+
+// 创建携带附加字段的子日志
+func (log *Logger) WithFields(fields Fields) *Logger {
+	child := &Logger{
+		ExtraCalldepth: log.ExtraCalldepth,
+		fields:         log.fields.merge(fields),
+		parent:         log.root(),
+	}
+
+	return child
+}
+
+// 以key, value方式创建子日志
+// 如: log.With("request_id", id, "user_id", uid)
+func (log *Logger) With(kv ...interface{}) *Logger {
+	return log.WithFields(makeFields(kv...))
+}
+
+// 设置最低输出等级, 低于该等级的信息在格式化之前直接丢弃
+// 可在运行时从其它goroutine安全调用
+func (log *Logger) SetLevel(level byte) {
+	atomic.StoreInt32(&log.root().level, int32(level)+1)
+}
+
+// 获取最低输出等级
+func (log *Logger) GetLevel() byte {
+	level := atomic.LoadInt32(&log.root().level)
+	if level == 0 {
+		return DEBUG
+	}
+
+	return byte(level - 1)
+}
+
+// 是否输出该等级信息
+func (log *Logger) Enabled(level byte) bool {
+	min := atomic.LoadInt32(&log.root().level)
+	return min == 0 || int32(level) < min
 }
 
-// 增加适配器
+// 增加适配器, 同名适配器已存在时返回错误
 func (log *Logger) AddAdapter(name string, adapter Adapter) error {
+	log = log.root()
 	log.lock.Lock()
 	defer log.lock.Unlock()
 	if _, ok := log.adapters[name]; ok {
+		return fmt.Errorf("logmo: adapter %q already exists", name)
+	}
+
+	adapters, counters := log.copyAdapters()
+	adapters[name] = adapter
+	counters[name] = log.newCounter(name, adapter)
+	log.adapters, log.counters = adapters, counters
+	return nil
+}
+
+// 替换适配器, 旧适配器写入等待中的信息后销毁, 不存在时直接增加
+// 日志已关闭时销毁新适配器并返回 ErrClosed
+func (log *Logger) ReplaceAdapter(name string, adapter Adapter) error {
+	log = log.root()
+	log.lock.Lock()
+	if atomic.LoadInt32(&log.closed) != 0 {
+		log.lock.Unlock()
+		shutdownAdapter(context.Background(), adapter)
+		return ErrClosed
+	}
+
+	old := log.adapters[name]
+	adapters, counters := log.copyAdapters()
+	adapters[name] = adapter
+	counters[name] = log.newCounter(name, adapter)
+	log.adapters, log.counters = adapters, counters
+	dispatching := log.swapDispatching()
+	log.lock.Unlock()
+
+	if old == nil {
 		return nil
 	}
 
-	log.adapters[name] = adapter
+	waitDispatch(context.Background(), dispatching)
+	if _, err := shutdownAdapter(context.Background(), old); err != nil {
+		return &AdapterError{Name: name, Err: err}
+	}
+
 	return nil
 }
 
+// 替换分发计数, 返回旧适配器集合的分发计数, 调用时需持有锁
+// 之后的分发使用新的适配器集合, 旧计数不会再增加
+func (log *Logger) swapDispatching() *sync.WaitGroup {
+	old := log.dispatching
+	if old == nil {
+		old = new(sync.WaitGroup)
+	}
+
+	log.dispatching = new(sync.WaitGroup)
+	return old
+}
+
+// 在ctx期限内等待进行中的分发完成, 之后不会再有信息写入已替换的适配器
+func waitDispatch(ctx context.Context, dispatching *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		dispatching.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // 删除适配器
 func (log *Logger) DeleteAdapter(name string) error {
+	log = log.root()
 	log.lock.Lock()
 	defer log.lock.Unlock()
 	if _, ok := log.adapters[name]; !ok {
 		return nil
 	}
 
-	delete(log.adapters, name)
+	adapters, counters := log.copyAdapters()
+	delete(adapters, name)
+	delete(counters, name)
+	log.adapters, log.counters = adapters, counters
 	return nil
 }
 
 // 获取适配器
 func (log *Logger) GetAdapter(name string) (Adapter, error) {
-	if _, ok := log.adapters[name]; ok {
-		return log.adapters[name], nil
+	adapters, _ := log.root().snapshot()
+	if adapter, ok := adapters[name]; ok {
+		return adapter, nil
 	}
 
 	return nil, errors.New(fmt.Sprintf("Adapter:%s not found", name))
 }
 
-// 输入信息
+// 复制适配器集合
+// 适配器集合只整体替换不原地修改, 写入时无需在遍历期间持有锁
+func (log *Logger) copyAdapters() (map[string]Adapter, map[string]*adapterCounter) {
+	adapters := make(map[string]Adapter, len(log.adapters)+1)
+	counters := make(map[string]*adapterCounter, len(log.counters)+1)
+	for name, adapter := range log.adapters {
+		adapters[name] = adapter
+	}
+
+	for name, counter := range log.counters {
+		counters[name] = counter
+	}
+
+	return adapters, counters
+}
+
+// 获取当前适配器集合用于分发, 并计入分发计数, 分发完成后需调用Done
+// 已关闭时返回nil
+func (log *Logger) acquire() (map[string]Adapter, map[string]*adapterCounter, *sync.WaitGroup) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	if atomic.LoadInt32(&log.closed) != 0 {
+		return nil, nil, nil
+	}
+
+	if log.dispatching == nil {
+		log.dispatching = new(sync.WaitGroup)
+	}
+
+	log.dispatching.Add(1)
+	return log.adapters, log.counters, log.dispatching
+}
+
+// 获取当前适配器集合
+func (log *Logger) snapshot() (map[string]Adapter, map[string]*adapterCounter) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	return log.adapters, log.counters
+}
+
+// 创建适配器计数器, 并设置异步错误上报
+func (log *Logger) newCounter(name string, adapter Adapter) *adapterCounter {
+	counter := new(adapterCounter)
+	if reporter, ok := adapter.(ErrorReporter); ok {
+		reporter.SetErrorHandler(func(err error) {
+			atomic.AddUint64(&counter.errors, 1)
+			log.handleError(&AdapterError{Name: name, Err: err})
+		})
+	}
+
+	return counter
+}
+
+// 设置错误处理, 适配器写入失败时调用
+func (log *Logger) SetErrorHandler(handler ErrorHandler) {
+	root := log.root()
+	root.lock.Lock()
+	root.errorHandler = handler
+	root.lock.Unlock()
+}
+
+// 处理错误
+func (log *Logger) handleError(err error) {
+	log.lock.Lock()
+	handler := log.errorHandler
+	log.lock.Unlock()
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// 获取各适配器写入统计
+func (log *Logger) Stats() map[string]AdapterStats {
+	log = log.root()
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	stats := make(map[string]AdapterStats, len(log.counters))
+	for name, counter := range log.counters {
+		stat := counter.stats()
+		if d, ok := log.adapters[name].(interface{ Dropped() uint64 }); ok {
+			stat.Dropped = d.Dropped()
+		}
+
+		if f, ok := log.adapters[name].(interface{ Filtered() uint64 }); ok {
+			stat.Filtered = f.Filtered()
+		}
+
+		stats[name] = stat
+	}
+
+	return stats
+}
+
+// 增加中间件, 在分发到各适配器之前按添加顺序执行, 同名中间件已存在时返回错误
+// 中间件修改的信息对所有适配器可见
+func (log *Logger) AddMiddleware(name string, m Middleware) error {
+	root := log.root()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	middlewares, err := root.middlewares.add(namedMiddleware{name: name, middleware: m})
+	if err != nil {
+		return err
+	}
+
+	root.middlewares = middlewares
+	return nil
+}
+
+// 删除中间件
+func (log *Logger) DeleteMiddleware(name string) error {
+	root := log.root()
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	root.middlewares = root.middlewares.remove(name)
+	return nil
+}
+
+// 获取被中间件过滤的信息总数
+func (log *Logger) Filtered() uint64 {
+	return atomic.LoadUint64(&log.root().filtered)
+}
+
+// 输入信息, 信息不设置模板
 func (log *Logger) Write(level byte, prefix string, msg string, data interface{}, sync bool) error {
-	log.counter++
+	if !log.Enabled(level) {
+		return nil
+	}
+
+	return log.output(2, level, prefix, "", msg, data, sync)
+}
+
+// 格式化信息并输出, 格式字符串作为信息模板
+func (log *Logger) outputf(calldepth int, level byte, prefix string, format string, v []interface{}, sync bool) error {
+	return log.output(calldepth+1, level, prefix, format, fmt.Sprintf(format, v...), nil, sync)
+}
+
+// 生成信息并分发到各适配器
+// calldepth 为相对于output调用者的调用深度
+func (log *Logger) output(calldepth int, level byte, prefix string, template string, msg string, data interface{}, sync bool) error {
+	var filename string
+	_, file, line, ok := runtime.Caller(calldepth + 1 + log.ExtraCalldepth)
+	if ok {
+		_, filename = path.Split(file)
+	}
+
+	return log.outputAt(time.Time{}, filename, line, level, prefix, template, msg, data, sync)
+}
+
+// 以指定的时间以及调用位置生成信息并分发到各适配器, at为零值时取当前时间
+func (log *Logger) outputAt(at time.Time, file string, line int, level byte, prefix string, template string, msg string, data interface{}, sync bool) error {
+	root := log.root()
+	if atomic.LoadInt32(&root.closed) != 0 {
+		return ErrClosed
+	}
+
+	counter := atomic.AddInt64(&root.counter, 1)
 	message := new(DefaultMessage)
 	message.Level = level
 	message.Message = msg
+	message.Template = template
 	message.Prefix = prefix
-	message.Time = time.Now()
+	message.Time = at
+	if at.IsZero() {
+		message.Time = time.Now()
+	}
+
 	message.Data = data
+	message.Fields = log.fields
 	message.Pid = os.Getpid()
-	message.Id = time.Now().UnixNano() + log.counter
-	_, file, line, ok := runtime.Caller(2 + log.ExtraCalldepth)
-	if ok {
-		_, filename := path.Split(file)
-		message.Line = line
-		message.File = filename
-	}
-	errs := []error{}
-	for _, adapter := range log.adapters {
-		if sync {
-			err := adapter.SyncWrite(message)
-			if err != nil {
-				errs = append(errs, err)
-			}
+	message.Id = time.Now().UnixNano() + counter
+	message.Line = line
+	message.File = file
+
+	root.lock.Lock()
+	middlewares := root.middlewares
+	root.lock.Unlock()
+	if !middlewares.handle(message) {
+		atomic.AddUint64(&root.filtered, 1)
+		return nil
+	}
 
-			continue
-		}
+	errs, err := root.dispatch(message, sync)
+	if err != nil {
+		return err
+	}
 
-		if adapter.IsAsync() {
-			err := adapter.AsyncWrite(message)
-			if err != nil {
-				errs = append(errs, err)
-			}
+	if len(errs) > 0 {
+		root.handleError(errs)
+		return errs
+	}
+	return nil
+}
+
+// 将信息分发到各适配器
+// 分发期间计入当前适配器集合的分发计数, 关闭或替换适配器时等待分发完成, 不会写入已关闭的适配器
+// 不持有锁, hook中写日志不会阻塞关闭
+func (log *Logger) dispatch(message Message, sync bool) (AdapterErrors, error) {
+	adapters, counters, dispatching := log.acquire()
+	if dispatching == nil {
+		return nil, ErrClosed
+	}
+
+	defer dispatching.Done()
+
+	var errs AdapterErrors
+	for name, adapter := range adapters {
+		var err error
+		if sync || !adapter.IsAsync() {
+			err = adapter.SyncWrite(message)
 		} else {
-			err := adapter.SyncWrite(message)
-			if err != nil {
-				errs = append(errs, err)
+			err = adapter.AsyncWrite(message)
+		}
+
+		counter := counters[name]
+		if err != nil {
+			errs = append(errs, &AdapterError{Name: name, Err: err})
+			if counter != nil {
+				atomic.AddUint64(&counter.errors, 1)
 			}
+			continue
 		}
-	}
 
-	if len(errs) > 0 {
-		return errs[0]
+		if counter != nil {
+			atomic.AddUint64(&counter.writes, 1)
+		}
 	}
-	return nil
+
+	return errs, nil
 }
 
 func (log *Logger) Flush() {
-	for _, adapter := range log.adapters {
+	adapters, _ := log.root().snapshot()
+	for _, adapter := range adapters {
 		adapter.Flush()
 	}
 }
 
+// 关闭日志, 等待全部信息写入
 func (log *Logger) Close() {
-	for _, adapter := range log.adapters {
-		adapter.Destroy()
+	log.CloseContext(context.Background())
+}
+
+// 在timeout内关闭日志, timeout小于等于0时等待全部信息写入
+func (log *Logger) Shutdown(timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
+	return log.CloseContext(ctx)
+}
+
+// 在ctx期限内关闭日志, 写入各适配器中等待的信息
+// 超过期限时返回 *ShutdownError, 关闭后再写入返回 ErrClosed
+func (log *Logger) CloseContext(ctx context.Context) error {
+	log = log.root()
+	if !atomic.CompareAndSwapInt32(&log.closed, 0, 1) {
+		return ErrClosed
+	}
+
+	log.lock.Lock()
+	adapters := log.adapters
 	log.adapters = make(map[string]Adapter)
+	log.counters = make(map[string]*adapterCounter)
+	dispatching := log.swapDispatching()
+	log.lock.Unlock()
+
+	// 等待进行中的分发完成, 超过期限时直接关闭适配器, 唤醒阻塞中的写入
+	waitDispatch(ctx, dispatching)
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		result ShutdownError
+	)
+
+	for name, adapter := range adapters {
+		wg.Add(1)
+		go func(name string, adapter Adapter) {
+			defer wg.Done()
+			lost, err := shutdownAdapter(ctx, adapter)
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Lost += lost
+			if err != nil {
+				result.Errors = append(result.Errors, &AdapterError{Name: name, Err: err})
+			}
+		}(name, adapter)
+	}
+
+	wg.Wait()
+	if result.Lost > 0 || len(result.Errors) > 0 {
+		return &result
+	}
+
+	return nil
+}
+
+// 关闭适配器, 返回未写入的信息数量
+func shutdownAdapter(ctx context.Context, adapter Adapter) (int, error) {
+	if s, ok := adapter.(interface {
+		Shutdown(ctx context.Context) (int, error)
+	}); ok {
+		return s.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		adapter.Destroy()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
 }
 
 // 紧急
-func (log *Logger) Emerg(format string, v ...interface{}) {
-	log.Write(EMERGENCY, "M", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Emerg(format string, v ...interface{}) error {
+	if !log.Enabled(EMERGENCY) {
+		return nil
+	}
+
+	return log.outputf(1, EMERGENCY, "M", format, v, false)
 }
 
 // 报警
-func (log *Logger) Alert(format string, v ...interface{}) {
-	log.Write(ALERT, "A", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Alert(format string, v ...interface{}) error {
+	if !log.Enabled(ALERT) {
+		return nil
+	}
+
+	return log.outputf(1, ALERT, "A", format, v, false)
 }
 
 // 严重
-func (log *Logger) Crit(format string, v ...interface{}) {
-	log.Write(CRITICAL, "C", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Crit(format string, v ...interface{}) error {
+	if !log.Enabled(CRITICAL) {
+		return nil
+	}
+
+	return log.outputf(1, CRITICAL, "C", format, v, false)
 }
 
 // 错误
-func (log *Logger) Err(format string, v ...interface{}) {
-	log.Write(ERROR, "E", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Err(format string, v ...interface{}) error {
+	if !log.Enabled(ERROR) {
+		return nil
+	}
+
+	return log.outputf(1, ERROR, "E", format, v, false)
 }
 
 // 警告
-func (log *Logger) Warn(format string, v ...interface{}) {
-	log.Write(WARNING, "W", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Warn(format string, v ...interface{}) error {
+	if !log.Enabled(WARNING) {
+		return nil
+	}
+
+	return log.outputf(1, WARNING, "W", format, v, false)
 }
 
 // 提示
-func (log *Logger) Notice(format string, v ...interface{}) {
-	log.Write(NOTICE, "N", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Notice(format string, v ...interface{}) error {
+	if !log.Enabled(NOTICE) {
+		return nil
+	}
+
+	return log.outputf(1, NOTICE, "N", format, v, false)
 }
 
 // 信息
-func (log *Logger) Info(format string, v ...interface{}) {
-	log.Write(INFO, "I", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Info(format string, v ...interface{}) error {
+	if !log.Enabled(INFO) {
+		return nil
+	}
+
+	return log.outputf(1, INFO, "I", format, v, false)
 }
 
 // 调试
-func (log *Logger) Debug(format string, v ...interface{}) {
-	log.Write(DEBUG, "D", fmt.Sprintf(format, v...), nil, false)
+func (log *Logger) Debug(format string, v ...interface{}) error {
+	if !log.Enabled(DEBUG) {
+		return nil
+	}
+
+	return log.outputf(1, DEBUG, "D", format, v, false)
 }
 
 // 紧急
-func (log *Logger) SyncEmerg(format string, v ...interface{}) {
-	log.Write(EMERGENCY, "M", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncEmerg(format string, v ...interface{}) error {
+	if !log.Enabled(EMERGENCY) {
+		return nil
+	}
+
+	return log.outputf(1, EMERGENCY, "M", format, v, true)
 }
 
 // 报警
-func (log *Logger) SyncAlert(format string, v ...interface{}) {
-	log.Write(ALERT, "A", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncAlert(format string, v ...interface{}) error {
+	if !log.Enabled(ALERT) {
+		return nil
+	}
+
+	return log.outputf(1, ALERT, "A", format, v, true)
 }
 
 // 严重
-func (log *Logger) SyncCrit(format string, v ...interface{}) {
-	log.Write(CRITICAL, "C", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncCrit(format string, v ...interface{}) error {
+	if !log.Enabled(CRITICAL) {
+		return nil
+	}
+
+	return log.outputf(1, CRITICAL, "C", format, v, true)
 }
 
 // 错误
-func (log *Logger) SyncErr(format string, v ...interface{}) {
-	log.Write(ERROR, "E", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncErr(format string, v ...interface{}) error {
+	if !log.Enabled(ERROR) {
+		return nil
+	}
+
+	return log.outputf(1, ERROR, "E", format, v, true)
 }
 
 // 警告
-func (log *Logger) SyncWarn(format string, v ...interface{}) {
-	log.Write(WARNING, "W", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncWarn(format string, v ...interface{}) error {
+	if !log.Enabled(WARNING) {
+		return nil
+	}
+
+	return log.outputf(1, WARNING, "W", format, v, true)
 }
 
 // 提示
-func (log *Logger) SyncNotice(format string, v ...interface{}) {
-	log.Write(NOTICE, "N", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncNotice(format string, v ...interface{}) error {
+	if !log.Enabled(NOTICE) {
+		return nil
+	}
+
+	return log.outputf(1, NOTICE, "N", format, v, true)
 }
 
 // 信息
-func (log *Logger) SyncInfo(format string, v ...interface{}) {
-	log.Write(INFO, "I", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncInfo(format string, v ...interface{}) error {
+	if !log.Enabled(INFO) {
+		return nil
+	}
+
+	return log.outputf(1, INFO, "I", format, v, true)
 }
 
 // 调试
-func (log *Logger) SyncDebug(format string, v ...interface{}) {
-	log.Write(DEBUG, "D", fmt.Sprintf(format, v...), nil, true)
+func (log *Logger) SyncDebug(format string, v ...interface{}) error {
+	if !log.Enabled(DEBUG) {
+		return nil
+	}
+
+	return log.outputf(1, DEBUG, "D", format, v, true)
+}
+
+func newLogger() *Logger {
+	return &Logger{
+		adapters: make(map[string]Adapter),
+		counters: make(map[string]*adapterCounter),
+	}
 }
 
 func New() *Logger {
-	logger := &Logger{adapters: make(map[string]Adapter)}
+	logger := newLogger()
 	console := NewAdapterConsole(10000)
 	go console.Run()
 	logger.AddAdapter("default", console)
@@ -240,43 +788,43 @@ func init() {
 }
 
 // 紧急
-func Emerg(format string, v ...interface{}) {
-	logmo.Emerg(format, v...)
+func Emerg(format string, v ...interface{}) error {
+	return logmo.Emerg(format, v...)
 }
 
 // 报警
-func Alert(format string, v ...interface{}) {
-	logmo.Alert(format, v...)
+func Alert(format string, v ...interface{}) error {
+	return logmo.Alert(format, v...)
 }
 
 // 严重
-func Crit(format string, v ...interface{}) {
-	logmo.Crit(format, v...)
+func Crit(format string, v ...interface{}) error {
+	return logmo.Crit(format, v...)
 }
 
 // 错误
-func Err(format string, v ...interface{}) {
-	logmo.Err(format, v...)
+func Err(format string, v ...interface{}) error {
+	return logmo.Err(format, v...)
 }
 
 // 警告
-func Warn(format string, v ...interface{}) {
-	logmo.Warn(format, v...)
+func Warn(format string, v ...interface{}) error {
+	return logmo.Warn(format, v...)
 }
 
 // 提示
-func Notice(format string, v ...interface{}) {
-	logmo.Notice(format, v...)
+func Notice(format string, v ...interface{}) error {
+	return logmo.Notice(format, v...)
 }
 
 // 信息
-func Info(format string, v ...interface{}) {
-	logmo.Info(format, v...)
+func Info(format string, v ...interface{}) error {
+	return logmo.Info(format, v...)
 }
 
 // 调试
-func Debug(format string, v ...interface{}) {
-	logmo.Debug(format, v...)
+func Debug(format string, v ...interface{}) error {
+	return logmo.Debug(format, v...)
 }
 
 func AddAdapter(name string, adapter Adapter) error {
@@ -291,6 +839,41 @@ func GetAdapter(name string) (Adapter, error) {
 	return logmo.GetAdapter(name)
 }
 
+// 默认日志增加中间件
+func AddMiddleware(name string, m Middleware) error {
+	return logmo.AddMiddleware(name, m)
+}
+
+// 默认日志删除中间件
+func DeleteMiddleware(name string) error {
+	return logmo.DeleteMiddleware(name)
+}
+
+// 创建携带附加字段的子日志
+func WithFields(fields Fields) *Logger {
+	return logmo.WithFields(fields)
+}
+
+// 以key, value方式创建子日志
+func With(kv ...interface{}) *Logger {
+	return logmo.With(kv...)
+}
+
+// 设置默认日志最低输出等级
+func SetLevel(level byte) {
+	logmo.SetLevel(level)
+}
+
+// 获取默认日志最低输出等级
+func GetLevel() byte {
+	return logmo.GetLevel()
+}
+
+// 在timeout内关闭默认日志
+func Shutdown(timeout time.Duration) error {
+	return logmo.Shutdown(timeout)
+}
+
 func SetExtraCalldepth(d int) {
 	logmo.ExtraCalldepth = d
 }
@@ -0,0 +1,211 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// context.Context 支持
+package logmo
+
+import (
+	"context"
+)
+
+// 从context中提取字段值, 返回false表示不存在
+type ContextExtractor func(ctx context.Context) (interface{}, bool)
+
+type contextExtractor struct {
+	key       string
+	extractor ContextExtractor
+}
+
+// 注册context字段提取器, 提取到的值以key作为字段名附加到信息
+// 同名提取器将被替换
+func (log *Logger) AddContextExtractor(key string, extractor ContextExtractor) {
+	log = log.root()
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	extractors := make([]contextExtractor, 0, len(log.extractors)+1)
+	for _, e := range log.extractors {
+		if e.key != key {
+			extractors = append(extractors, e)
+		}
+	}
+
+	log.extractors = append(extractors, contextExtractor{key: key, extractor: extractor})
+}
+
+// 删除context字段提取器
+func (log *Logger) DeleteContextExtractor(key string) {
+	log = log.root()
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	extractors := make([]contextExtractor, 0, len(log.extractors))
+	for _, e := range log.extractors {
+		if e.key != key {
+			extractors = append(extractors, e)
+		}
+	}
+
+	log.extractors = extractors
+}
+
+// 从context中提取字段
+func (log *Logger) contextFields(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+
+	root := log.root()
+	root.lock.Lock()
+	extractors := root.extractors
+	root.lock.Unlock()
+
+	var fields Fields
+	for _, e := range extractors {
+		if v, ok := e.extractor(ctx); ok {
+			if fields == nil {
+				fields = make(Fields, len(extractors))
+			}
+
+			fields[e.key] = v
+		}
+	}
+
+	return fields
+}
+
+// 创建携带context字段的子日志
+func (log *Logger) WithContext(ctx context.Context) *Logger {
+	return log.WithFields(log.contextFields(ctx))
+}
+
+// 紧急
+func (log *Logger) EmergContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(EMERGENCY) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, EMERGENCY, "M", format, v, false)
+}
+
+// 报警
+func (log *Logger) AlertContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(ALERT) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, ALERT, "A", format, v, false)
+}
+
+// 严重
+func (log *Logger) CritContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(CRITICAL) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, CRITICAL, "C", format, v, false)
+}
+
+// 错误
+func (log *Logger) ErrContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(ERROR) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, ERROR, "E", format, v, false)
+}
+
+// 警告
+func (log *Logger) WarnContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(WARNING) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, WARNING, "W", format, v, false)
+}
+
+// 提示
+func (log *Logger) NoticeContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(NOTICE) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, NOTICE, "N", format, v, false)
+}
+
+// 信息
+func (log *Logger) InfoContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(INFO) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, INFO, "I", format, v, false)
+}
+
+// 调试
+func (log *Logger) DebugContext(ctx context.Context, format string, v ...interface{}) error {
+	if !log.Enabled(DEBUG) {
+		return nil
+	}
+
+	return log.WithContext(ctx).outputf(1, DEBUG, "D", format, v, false)
+}
+
+// 注册默认日志context字段提取器
+func AddContextExtractor(key string, extractor ContextExtractor) {
+	logmo.AddContextExtractor(key, extractor)
+}
+
+// 创建携带context字段的子日志
+func WithContext(ctx context.Context) *Logger {
+	return logmo.WithContext(ctx)
+}
+
+// 紧急
+func EmergContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.EmergContext(ctx, format, v...)
+}
+
+// 报警
+func AlertContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.AlertContext(ctx, format, v...)
+}
+
+// 严重
+func CritContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.CritContext(ctx, format, v...)
+}
+
+// 错误
+func ErrContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.ErrContext(ctx, format, v...)
+}
+
+// 警告
+func WarnContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.WarnContext(ctx, format, v...)
+}
+
+// 提示
+func NoticeContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.NoticeContext(ctx, format, v...)
+}
+
+// 信息
+func InfoContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.InfoContext(ctx, format, v...)
+}
+
+// 调试
+func DebugContext(ctx context.Context, format string, v ...interface{}) error {
+	return logmo.DebugContext(ctx, format, v...)
+}
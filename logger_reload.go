@@ -0,0 +1,191 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 配置热加载
+package logmo
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 检查配置文件的默认间隔
+const DefaultWatchInterval = time.Second
+
+// 按配置替换全部适配器以及日志等级
+// 新适配器创建失败时不做任何修改; 替换后旧适配器写入等待中的信息后销毁
+func (log *Logger) Reload(cfg *Config) error {
+	level, list, err := cfg.build()
+	if err != nil {
+		return err
+	}
+
+	log = log.root()
+	adapters := make(map[string]Adapter, len(list))
+	counters := make(map[string]*adapterCounter, len(list))
+	for _, item := range list {
+		go item.adapter.Run()
+		adapters[item.name] = item.adapter
+		counters[item.name] = log.newCounter(item.name, item.adapter)
+	}
+
+	// 在锁内检查是否已关闭, 关闭后不再安装新适配器
+	log.lock.Lock()
+	if atomic.LoadInt32(&log.closed) != 0 {
+		log.lock.Unlock()
+		shutdownAdapters(adapters)
+		return ErrClosed
+	}
+
+	old := log.adapters
+	log.adapters, log.counters = adapters, counters
+	log.SetLevel(level)
+	dispatching := log.swapDispatching()
+	log.lock.Unlock()
+
+	// 旧适配器在进行中的分发完成后再销毁, 避免丢失信息
+	waitDispatch(context.Background(), dispatching)
+	if errs := shutdownAdapters(old); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// 并发销毁适配器, 返回销毁失败的错误
+func shutdownAdapters(adapters map[string]Adapter) AdapterErrors {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		errs  AdapterErrors
+	)
+
+	for name, adapter := range adapters {
+		wg.Add(1)
+		go func(name string, adapter Adapter) {
+			defer wg.Done()
+			if _, err := shutdownAdapter(context.Background(), adapter); err != nil {
+				mutex.Lock()
+				errs = append(errs, &AdapterError{Name: name, Err: err})
+				mutex.Unlock()
+			}
+		}(name, adapter)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// 读取配置文件并重新加载
+func (log *Logger) ReloadFile(filename string) error {
+	cfg, err := LoadConfigFile(filename)
+	if err != nil {
+		return err
+	}
+
+	return log.Reload(cfg)
+}
+
+// 定时检查配置文件, 修改后重新加载, 错误通过错误处理上报
+// interval小于等于0时使用 DefaultWatchInterval, 返回停止函数
+func (log *Logger) WatchConfigFile(filename string, interval time.Duration) func() {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last os.FileInfo
+		if info, err := os.Stat(filename); err == nil {
+			last = info
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(filename)
+			if err != nil {
+				continue
+			}
+
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+
+			last = info
+			if err := log.ReloadFile(filename); err != nil {
+				log.root().handleError(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
+
+// 收到信号时重新加载配置文件, 未指定信号时使用SIGHUP
+// 返回停止函数
+func (log *Logger) ReloadOnSignal(filename string, sig ...os.Signal) func() {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(ch, sig...)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ch:
+				if err := log.ReloadFile(filename); err != nil {
+					log.root().handleError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(stop)
+		})
+	}
+}
+
+// 按配置重新加载默认日志
+func Reload(cfg *Config) error {
+	return logmo.Reload(cfg)
+}
+
+// 替换默认日志的适配器
+func ReplaceAdapter(name string, adapter Adapter) error {
+	return logmo.ReplaceAdapter(name, adapter)
+}
@@ -0,0 +1,170 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// log/slog桥接
+package logmo
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"runtime"
+)
+
+// 与logmo等级对应的slog等级, slog只定义了DEBUG, INFO, WARN, ERROR
+const (
+	SLOG_LEVEL_NOTICE    = slog.LevelInfo + 2
+	SLOG_LEVEL_CRITICAL  = slog.LevelError + 4
+	SLOG_LEVEL_ALERT     = slog.LevelError + 8
+	SLOG_LEVEL_EMERGENCY = slog.LevelError + 12
+)
+
+// 日志等级转换为slog等级
+func SlogLevel(level byte) slog.Level {
+	switch level {
+	case EMERGENCY:
+		return SLOG_LEVEL_EMERGENCY
+	case ALERT:
+		return SLOG_LEVEL_ALERT
+	case CRITICAL:
+		return SLOG_LEVEL_CRITICAL
+	case ERROR:
+		return slog.LevelError
+	case WARNING:
+		return slog.LevelWarn
+	case NOTICE:
+		return SLOG_LEVEL_NOTICE
+	case INFO:
+		return slog.LevelInfo
+	}
+
+	return slog.LevelDebug
+}
+
+// slog等级转换为日志等级, 介于两个等级之间时取较低的等级
+func LevelFromSlog(level slog.Level) byte {
+	switch {
+	case level >= SLOG_LEVEL_EMERGENCY:
+		return EMERGENCY
+	case level >= SLOG_LEVEL_ALERT:
+		return ALERT
+	case level >= SLOG_LEVEL_CRITICAL:
+		return CRITICAL
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARNING
+	case level >= SLOG_LEVEL_NOTICE:
+		return NOTICE
+	case level >= slog.LevelInfo:
+		return INFO
+	}
+
+	return DEBUG
+}
+
+// 以Logger输出的slog.Handler
+// 属性转为附加字段, 分组以 "." 连接作为字段名前缀
+type SlogHandler struct {
+	log *Logger
+
+	// WithAttrs添加的字段
+	fields Fields
+
+	// WithGroup添加的分组前缀
+	group string
+}
+
+// 创建以log输出的slog.Handler
+func NewSlogHandler(log *Logger) *SlogHandler {
+	return &SlogHandler{log: log}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.log.Enabled(LevelFromSlog(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := LevelFromSlog(r.Level)
+	if !h.log.Enabled(level) {
+		return nil
+	}
+
+	fields := h.fields.merge(h.log.contextFields(ctx))
+	if r.NumAttrs() > 0 {
+		record := make(Fields, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			addSlogAttr(record, h.group, a)
+			return true
+		})
+
+		fields = fields.merge(record)
+	}
+
+	var file string
+	var line int
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		_, file = path.Split(frame.File)
+		line = frame.Line
+	}
+
+	return h.log.WithFields(fields).outputAt(r.Time, file, line, level, levelPrefix(level), r.Message, r.Message, nil, false)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := make(Fields, len(attrs))
+	for _, a := range attrs {
+		addSlogAttr(fields, h.group, a)
+	}
+
+	return &SlogHandler{log: h.log, fields: h.fields.merge(fields), group: h.group}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &SlogHandler{log: h.log, fields: h.fields, group: h.group + name + "."}
+}
+
+// 将属性加入字段, 分组展开为 "分组.字段"
+func addSlogAttr(fields Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		fields[prefix+a.Key] = a.Value.Any()
+		return
+	}
+
+	// 空名称分组的属性直接并入上级
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+
+	for _, ga := range a.Value.Group() {
+		addSlogAttr(fields, prefix, ga)
+	}
+}
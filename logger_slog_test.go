@@ -0,0 +1,84 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// slog桥接测试
+package logmo
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type slogKey struct{}
+
+func TestSlogHandler(t *testing.T) {
+	log, mem := newMemoryLogger()
+	log.SetLevel(INFO)
+	log.AddContextExtractor("trace_id", func(ctx context.Context) (interface{}, bool) {
+		v, ok := ctx.Value(slogKey{}).(string)
+		return v, ok
+	})
+
+	logger := slog.New(NewSlogHandler(log)).With("service", "api").WithGroup("req")
+	ctx := context.WithValue(context.Background(), slogKey{}, "abc")
+	_, _, line, _ := runtime.Caller(0)
+	logger.WarnContext(ctx, "slow request", "ms", 250, slog.Group("user", "id", 7), slog.Group("", "inline", true))
+
+	m := mem.last()
+	if m.GetMessage() != "slow request" || messageTemplate(m) != "slow request" || m.GetLevel() != WARNING || m.GetPrefix() != "W" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if m.GetFile() != "logger_slog_test.go" || m.GetLine() != line+1 {
+		t.Fatalf("unexpected caller %s:%d", m.GetFile(), m.GetLine())
+	}
+
+	expected := Fields{"service": "api", "trace_id": "abc", "req.ms": int64(250), "req.user.id": int64(7), "req.inline": true}
+	if m.GetFields().String() != expected.String() {
+		t.Fatalf("unexpected fields %s", m.GetFields())
+	}
+
+	logger.Debug("filtered")
+	logger.Log(ctx, SLOG_LEVEL_CRITICAL+1, "critical")
+	if len(mem.messages) != 2 || mem.last().GetLevel() != CRITICAL {
+		t.Fatalf("unexpected messages %d", len(mem.messages))
+	}
+
+	// 使用记录的时间, 为零值时取当前时间
+	at := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewSlogHandler(log)
+	handler.Handle(ctx, slog.NewRecord(at, slog.LevelInfo, "replayed", 0))
+	if !mem.last().GetTime().Equal(at) {
+		t.Fatalf("unexpected time %s", mem.last().GetTime())
+	}
+
+	handler.Handle(ctx, slog.NewRecord(time.Time{}, slog.LevelInfo, "untimed", 0))
+	if time.Since(mem.last().GetTime()) > time.Minute {
+		t.Fatalf("unexpected time %s", mem.last().GetTime())
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	for level := byte(EMERGENCY); level <= DEBUG; level++ {
+		if LevelFromSlog(SlogLevel(level)) != level {
+			t.Fatalf("%s: level mapping is not symmetric", LevelName(level))
+		}
+	}
+}
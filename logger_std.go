@@ -0,0 +1,92 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 标准库log桥接
+package logmo
+
+import (
+	stdlog "log"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 将标准库log的输出转为日志信息
+// 同步写入, log.Fatal 以及 log.Panic 输出后进程随即退出, 异步写入的信息会丢失
+type stdWriter struct {
+	log   *Logger
+	level byte
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	if !w.log.Enabled(w.level) {
+		return len(p), nil
+	}
+
+	msg := strings.TrimSuffix(string(p), "\n")
+	file, line := w.caller()
+	if err := w.log.outputAt(time.Time{}, file, line, w.level, levelPrefix(w.level), "", msg, nil, true); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// 获取标准库log的调用位置, 跳过log包内部以及桥接自身的调用
+func (w *stdWriter) caller() (string, int) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	inLog, skip := false, w.log.ExtraCalldepth
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "log.") {
+			inLog = true
+		} else if inLog {
+			if skip <= 0 {
+				_, file := path.Split(frame.File)
+				return file, frame.Line
+			}
+
+			skip--
+		}
+
+		if !more {
+			return "", 0
+		}
+	}
+}
+
+// 创建输出到本日志的标准库 *log.Logger, 信息以level等级写入
+// 调用位置为调用 *log.Logger 的位置
+func (log *Logger) StdLogger(level byte) *stdlog.Logger {
+	return stdlog.New(&stdWriter{log: log, level: level}, "", 0)
+}
+
+// 将标准库log的全局输出重定向到本日志, 信息以level等级写入
+// 返回恢复函数, 恢复原有的输出以及格式
+func (log *Logger) RedirectStdLog(level byte) func() {
+	out, flags := stdlog.Writer(), stdlog.Flags()
+	stdlog.SetOutput(&stdWriter{log: log, level: level})
+	stdlog.SetFlags(0)
+	return func() {
+		stdlog.SetOutput(out)
+		stdlog.SetFlags(flags)
+	}
+}
+
+// 将标准库log的全局输出重定向到默认日志
+func RedirectStdLog(level byte) func() {
+	return logmo.RedirectStdLog(level)
+}
@@ -0,0 +1,330 @@
+// Copyright 2015 doublemo. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 日志测试
+package logmo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	stdlog "log"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试用适配器, 同步记录所有信息
+type memoryAdapter struct {
+	lock     sync.Mutex
+	messages []Message
+	err      error
+}
+
+func (adapter *memoryAdapter) SyncWrite(message Message) error {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	if adapter.err != nil {
+		return adapter.err
+	}
+
+	adapter.messages = append(adapter.messages, message)
+	return nil
+}
+
+func (adapter *memoryAdapter) AsyncWrite(message Message) error {
+	return adapter.SyncWrite(message)
+}
+
+func (adapter *memoryAdapter) Async(b bool)                           {}
+func (adapter *memoryAdapter) IsAsync() bool                          { return false }
+func (adapter *memoryAdapter) SetFormatter(formatter Formatter) error { return nil }
+func (adapter *memoryAdapter) AddHook(name string, hook Hook) error   { return nil }
+func (adapter *memoryAdapter) DeleteHook(name string) error           { return nil }
+func (adapter *memoryAdapter) Destroy()                               {}
+func (adapter *memoryAdapter) Run()                                   {}
+func (adapter *memoryAdapter) Flush()                                 {}
+
+func (adapter *memoryAdapter) last() Message {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+	if len(adapter.messages) == 0 {
+		return nil
+	}
+
+	return adapter.messages[len(adapter.messages)-1]
+}
+
+func newMemoryLogger() (*Logger, *memoryAdapter) {
+	log := newLogger()
+	mem := new(memoryAdapter)
+	log.AddAdapter("memory", mem)
+	return log, mem
+}
+
+func TestLoggerWithFields(t *testing.T) {
+	log, mem := newMemoryLogger()
+	child := log.With("request_id", "r-1").WithFields(Fields{"user_id": 42})
+	child.Info("hello %s", "world")
+
+	m := mem.last()
+	if m == nil {
+		t.Fatal("message not written")
+	}
+
+	fields := m.GetFields()
+	if fields["request_id"] != "r-1" || fields["user_id"] != 42 {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	if m.GetFile() != "logger_test.go" {
+		t.Fatalf("unexpected caller file: %s", m.GetFile())
+	}
+
+	b, err := new(FormatterText).Format(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(string(b), "hello world request_id=r-1 user_id=42") {
+		t.Fatalf("unexpected text: %s", b)
+	}
+
+	log.Info("plain")
+	if len(mem.last().GetFields()) != 0 {
+		t.Fatal("parent logger must not carry child fields")
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	log, mem := newMemoryLogger()
+	child := log.With("k", "v")
+	log.SetLevel(WARNING)
+	if child.GetLevel() != WARNING {
+		t.Fatal("child logger must share level with parent")
+	}
+
+	child.Info("dropped")
+	log.Notice("dropped")
+	log.Write(DEBUG, "D", "dropped", nil, true)
+	log.Warn("kept")
+	if len(mem.messages) != 1 || mem.last().GetMessage() != "kept" {
+		t.Fatalf("unexpected messages: %d", len(mem.messages))
+	}
+}
+
+func TestLoggerZeroValue(t *testing.T) {
+	log, mem := new(Logger), new(memoryAdapter)
+	log.AddAdapter("memory", mem)
+	if log.GetLevel() != DEBUG {
+		t.Fatalf("zero value logger must default to DEBUG, got %s", LevelName(log.GetLevel()))
+	}
+
+	log.Debug("kept")
+	log.Info("kept")
+	if len(mem.messages) != 2 {
+		t.Fatalf("unexpected messages: %d", len(mem.messages))
+	}
+
+	log.SetLevel(EMERGENCY)
+	log.Alert("dropped")
+	log.Emerg("kept")
+	if len(mem.messages) != 3 || log.GetLevel() != EMERGENCY {
+		t.Fatalf("unexpected messages: %d", len(mem.messages))
+	}
+}
+
+func TestLoggerAdapterErrors(t *testing.T) {
+	log, mem := newMemoryLogger()
+	log.AddAdapter("ok", new(memoryAdapter))
+	mem.err = errors.New("disk full")
+
+	var handled error
+	log.SetErrorHandler(func(err error) {
+		handled = err
+	})
+
+	err := log.Err("lost")
+	var errs AdapterErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Name != "memory" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if handled == nil || !errors.Is(handled, mem.err) {
+		t.Fatalf("error handler not called: %v", handled)
+	}
+
+	stats := log.Stats()
+	if stats["memory"].Errors != 1 || stats["ok"].Writes != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+type traceKey struct{}
+
+func TestLoggerContext(t *testing.T) {
+	log, mem := newMemoryLogger()
+	log.AddContextExtractor("trace_id", func(ctx context.Context) (interface{}, bool) {
+		v, ok := ctx.Value(traceKey{}).(string)
+		return v, ok
+	})
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "t-1")
+	log.With("tenant", "a").InfoContext(ctx, "hello")
+	fields := mem.last().GetFields()
+	if fields["trace_id"] != "t-1" || fields["tenant"] != "a" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	if mem.last().GetFile() != "logger_test.go" {
+		t.Fatalf("unexpected caller file: %s", mem.last().GetFile())
+	}
+
+	log.InfoContext(context.Background(), "no trace")
+	if _, ok := mem.last().GetFields()["trace_id"]; ok {
+		t.Fatal("trace_id must be absent")
+	}
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	log, mem := newMemoryLogger()
+	log.AddMiddleware("redact", MiddlewareFunc(func(message MutableMessage) HookResult {
+		message.SetMessage(strings.Replace(message.GetMessage(), "secret", "***", -1))
+		return HOOK_KEEP
+	}))
+	log.AddMiddleware("drop", MiddlewareFunc(func(message MutableMessage) HookResult {
+		if message.GetFields()["skip"] != nil {
+			return HOOK_DROP
+		}
+
+		return HOOK_KEEP
+	}))
+
+	if err := log.AddMiddleware("drop", MiddlewareFunc(nil)); err == nil {
+		t.Fatal("duplicate middleware must fail")
+	}
+
+	log.Info("password=%s", "secret")
+	if m := mem.last(); m.GetMessage() != "password=***" {
+		t.Fatalf("unexpected message %q", m.GetMessage())
+	}
+
+	log.With("skip", true).Info("dropped")
+	if len(mem.messages) != 1 || log.Filtered() != 1 {
+		t.Fatalf("unexpected state: %d messages, %d filtered", len(mem.messages), log.Filtered())
+	}
+}
+
+func TestLoggerStdLog(t *testing.T) {
+	log, mem := newMemoryLogger()
+	std := log.StdLogger(WARNING)
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("connection %d reset", 7)
+
+	m := mem.last()
+	if m.GetMessage() != "connection 7 reset" || m.GetLevel() != WARNING || m.GetPrefix() != "W" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if m.GetFile() != "logger_test.go" || m.GetLine() != line+1 {
+		t.Fatalf("unexpected caller %s:%d", m.GetFile(), m.GetLine())
+	}
+
+	var buf bytes.Buffer
+	stdlog.SetOutput(&buf)
+	restore := log.RedirectStdLog(ERROR)
+	_, _, line, _ = runtime.Caller(0)
+	stdlog.Println("redirected")
+	restore()
+	stdlog.Println("restored")
+	stdlog.SetOutput(os.Stderr)
+
+	if m := mem.messages[1]; m.GetMessage() != "redirected" || m.GetLevel() != ERROR || m.GetLine() != line+1 {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if len(mem.messages) != 2 || !strings.HasSuffix(buf.String(), "restored\n") {
+		t.Fatalf("std log must be restored: %d messages, output %q", len(mem.messages), buf.String())
+	}
+
+	// 异步适配器未启动Run时也已写入
+	sink := new(memorySink)
+	log.AddAdapter("async", NewBaseAdapter(sink, 10))
+	log.StdLogger(ERROR).Print("fatal")
+	if sink.count() != 1 {
+		t.Fatalf("std log must be written synchronously, got %d lines", sink.count())
+	}
+}
+
+func TestLoggerConcurrent(t *testing.T) {
+	log, mem := newMemoryLogger()
+	sublog := log.With("worker", true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				log.Info("parent %d", j)
+				sublog.Info("child %d", j)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if len(mem.messages) != 1600 {
+		t.Fatalf("expected 1600 messages, got %d", len(mem.messages))
+	}
+}
+
+// 写入时阻塞直到release关闭的输出
+type stallSink struct {
+	release chan struct{}
+}
+
+func (sink *stallSink) WriteMessage(message Message, b []byte) error {
+	<-sink.release
+	return nil
+}
+
+func (sink *stallSink) Sync() error  { return nil }
+func (sink *stallSink) Close() error { return nil }
+
+func TestLoggerShutdownDeadline(t *testing.T) {
+	release := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(release) })
+	adapter := NewBaseAdapter(&stallSink{release: release}, 1)
+	log := newLogger()
+	log.AddAdapter("stall", adapter)
+	go adapter.Run()
+
+	// 输出阻塞, 通道已满, 之后的写入阻塞在通道上
+	for i := 0; i < 4; i++ {
+		go log.Info("specific language governing permissions")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	if err := log.Shutdown(100 * time.Millisecond); err == nil {
+		t.Fatal("shutdown must report the stalled adapter")
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("shutdown ignored the deadline: %s", d)
+	}
+}
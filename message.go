@@ -35,6 +35,9 @@ type Message interface {
     // 获取附加数据
     GetData() interface{} 
     
+    // 获取附加字段
+    GetFields() Fields
+    
     // 获取时间
     GetTime() time.Time
     
@@ -53,17 +56,28 @@ type DefaultMessage struct {
     File  string
     Line  int
     Message string
+    Template string
     Data  interface{}
+    Fields Fields
     Time  time.Time
     Prefix string
     Pid int
     Id  int64
+    
+    // Fields是否为本信息独有, 修改共享的字段前需复制
+    ownFields bool
 }
 
 func (msg *DefaultMessage) GetMessage() string {
     return msg.Message
 }
 
+// 获取信息模板, 即格式化前的格式字符串
+// Write以及标准库log写入的信息没有模板, 返回空
+func (msg *DefaultMessage) GetTemplate() string {
+    return msg.Template
+}
+
 func (msg *DefaultMessage) GetLevel() byte {
     return msg.Level
 }
@@ -80,6 +94,10 @@ func (msg *DefaultMessage) GetData() interface{} {
     return msg.Data
 }
 
+func (msg *DefaultMessage) GetFields() Fields {
+    return msg.Fields
+}
+
 func (msg *DefaultMessage) GetTime() time.Time {
     return msg.Time
 }
@@ -94,4 +112,82 @@ func (msg *DefaultMessage) GetPID() int {
 
 func (msg *DefaultMessage) GetID() int64 {
     return msg.Id
-} 
\ No newline at end of file
+} 
+
+func (msg *DefaultMessage) SetMessage( message string ) {
+    msg.Message = message
+}
+
+func (msg *DefaultMessage) SetLevel( level byte ) {
+    msg.Level = level
+}
+
+func (msg *DefaultMessage) SetPrefix( prefix string ) {
+    msg.Prefix = prefix
+}
+
+func (msg *DefaultMessage) SetData( data interface{} ) {
+    msg.Data = data
+}
+
+// 设置附加字段, 字段与子日志共享, 首次修改时复制
+func (msg *DefaultMessage) SetField( key string, value interface{} ) {
+    msg.ownedFields()[key] = value
+}
+
+func (msg *DefaultMessage) DeleteField( key string ) {
+    if _, ok := msg.Fields[key]; ok {
+        delete(msg.ownedFields(), key)
+    }
+}
+
+func (msg *DefaultMessage) ownedFields() Fields {
+    if !msg.ownFields {
+        fields := make(Fields, len(msg.Fields) + 1)
+        for k, v := range msg.Fields {
+            fields[k] = v
+        }
+        
+        msg.Fields    = fields
+        msg.ownFields = true
+    }
+    
+    return msg.Fields
+}
+
+// 复制信息, 字段在修改时才复制
+func (msg *DefaultMessage) Clone() *DefaultMessage {
+    clone := *msg
+    clone.ownFields = false
+    return &clone
+}
+
+// 复制任意信息为可修改的信息
+func cloneMessage( message Message ) *DefaultMessage {
+    if msg, ok := message.(*DefaultMessage); ok {
+        return msg.Clone()
+    }
+    
+    return &DefaultMessage{
+        Level    : message.GetLevel(),
+        File     : message.GetFile(),
+        Line     : message.GetLine(),
+        Message  : message.GetMessage(),
+        Template : messageTemplate(message),
+        Data     : message.GetData(),
+        Fields   : message.GetFields(),
+        Time     : message.GetTime(),
+        Prefix   : message.GetPrefix(),
+        Pid      : message.GetPID(),
+        Id       : message.GetID(),
+    }
+}
+
+// 获取信息模板, 信息未提供模板时返回空
+func messageTemplate( message Message ) string {
+    if m, ok := message.(interface{ GetTemplate() string }); ok {
+        return m.GetTemplate()
+    }
+    
+    return ""
+}